@@ -7,9 +7,12 @@ import (
 
 	"naevis/db"
 	"naevis/models"
+	"naevis/utils"
 
 	"github.com/julienschmidt/httprouter"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // GetReports returns all non‐resolved reports for the admin UI.
@@ -122,3 +125,126 @@ func GetReports(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 // 	w.Header().Set("Content-Type", "application/json")
 // 	json.NewEncoder(w).Encode(reports)
 // }
+
+// GetReportTargets lists reported items with their reports rolled up, so an
+// item reported many times shows up once.
+//
+// Endpoint: GET /api/v1/admin/report-targets?status=open&type=comment&page=1&limit=20
+//
+// status is "open" (default), "closed" or "all". Targets with the most
+// reports come first.
+func GetReportTargets(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	q := r.URL.Query()
+	match := bson.M{}
+	switch q.Get("status") {
+	case "", "open":
+		match["status"] = bson.M{"$nin": []string{"resolved", "rejected"}}
+	case "closed":
+		match["status"] = bson.M{"$in": []string{"resolved", "rejected"}}
+	}
+	if t := q.Get("type"); t != "" {
+		match["targetType"] = t
+	}
+
+	page := utils.ParseInt(q.Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit := utils.ParseInt(q.Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             bson.M{"targetType": "$targetType", "targetId": "$targetId"},
+			"count":           bson.M{"$sum": 1},
+			"reasons":         bson.M{"$addToSet": "$reason"},
+			"reportIds":       bson.M{"$push": bson.M{"$toString": "$_id"}},
+			"firstReportedAt": bson.M{"$min": "$createdAt"},
+			"lastReportedAt":  bson.M{"$max": "$createdAt"},
+		}}},
+		{{Key: "$project", Value: bson.M{
+			"_id":             0,
+			"targetType":      "$_id.targetType",
+			"targetId":        "$_id.targetId",
+			"count":           1,
+			"reasons":         1,
+			"reportIds":       1,
+			"firstReportedAt": 1,
+			"lastReportedAt":  1,
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "lastReportedAt", Value: -1}}}},
+		{{Key: "$facet", Value: bson.M{
+			"targets": bson.A{
+				bson.M{"$skip": (page - 1) * limit},
+				bson.M{"$limit": limit},
+			},
+			"total": bson.A{bson.M{"$count": "n"}},
+		}}},
+	}
+
+	cursor, err := db.ReportsCollection.Aggregate(r.Context(), pipeline)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch reports"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	var result []struct {
+		Targets []models.ReportTarget `bson:"targets"`
+		Total   []struct {
+			N int `bson:"n"`
+		} `bson:"total"`
+	}
+	if err := cursor.All(r.Context(), &result); err != nil {
+		http.Error(w, `{"error":"Error processing reports"}`, http.StatusInternalServerError)
+		return
+	}
+
+	targets, total := []models.ReportTarget{}, 0
+	if len(result) > 0 {
+		targets = append(targets, result[0].Targets...)
+		if len(result[0].Total) > 0 {
+			total = result[0].Total[0].N
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"targets": targets,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// GetTargetReports returns every report about one target, newest first,
+// including the moderation actions recorded on them.
+//
+// Endpoint: GET /api/v1/admin/report-targets/:targettype/:targetid
+func GetTargetReports(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	filter := bson.M{"targetType": ps.ByName("targettype"), "targetId": ps.ByName("targetid")}
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+
+	cursor, err := db.ReportsCollection.Find(r.Context(), filter, opts)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch reports"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	reports := []models.Report{}
+	if err := cursor.All(r.Context(), &reports); err != nil {
+		http.Error(w, `{"error":"Error processing reports"}`, http.StatusInternalServerError)
+		return
+	}
+	if len(reports) == 0 {
+		http.Error(w, `{"error":"No reports for this target"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
@@ -0,0 +1,97 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/globals"
+	"naevis/middleware"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetUserRoles returns the roles a user holds.
+//
+// Endpoint: GET /api/v1/admin/users/:userid/roles
+func GetUserRoles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var user struct {
+		UserID string   `json:"userid" bson:"userid"`
+		Role   []string `json:"roles"  bson:"role"`
+	}
+	err := db.UserCollection.FindOne(r.Context(), bson.M{"userid": ps.ByName("userid")},
+		options.FindOne().SetProjection(bson.M{"userid": 1, "role": 1})).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error":"Failed to fetch user"}`, http.StatusInternalServerError)
+		return
+	}
+	if user.Role == nil {
+		user.Role = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user)
+}
+
+// SetUserRoles replaces a user's roles.
+//
+// Endpoint: PUT /api/v1/admin/users/:userid/roles
+//
+// Body: { "roles": ["buyer", "farmer"] }
+//
+// Admins cannot drop their own admin role, so there is always a way back in.
+func SetUserRoles(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := ps.ByName("userid")
+
+	var payload struct {
+		Roles []string `json:"roles"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"error":"Invalid JSON payload"}`, http.StatusBadRequest)
+		return
+	}
+
+	roles := []string{}
+	seen := map[string]bool{}
+	for _, role := range payload.Roles {
+		role = strings.ToLower(strings.TrimSpace(role))
+		if !middleware.IsRole(role) {
+			http.Error(w, `{"error":"Unknown role: `+role+`"}`, http.StatusBadRequest)
+			return
+		}
+		if !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	caller, _ := r.Context().Value(globals.UserIDKey).(string)
+	if userID == caller && !seen[middleware.RoleAdmin] {
+		http.Error(w, `{"error":"You cannot remove your own admin role"}`, http.StatusBadRequest)
+		return
+	}
+
+	res, err := db.UserCollection.UpdateOne(r.Context(),
+		bson.M{"userid": userID},
+		bson.M{"$set": bson.M{"role": roles, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to update roles"}`, http.StatusInternalServerError)
+		return
+	}
+	if res.MatchedCount == 0 {
+		http.Error(w, `{"error":"User not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"userid": userID, "roles": roles})
+}
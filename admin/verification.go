@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/globals"
+	"naevis/models"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GetVerificationRequests returns farm verification requests for the admin UI.
+//
+// Endpoint: GET /api/v1/admin/verifications?status=pending
+//
+// Defaults to pending requests, oldest first.
+func GetVerificationRequests(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	status := r.URL.Query().Get("status")
+	if status == "" {
+		status = "pending"
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": 1})
+	cursor, err := db.FarmVerificationsCollection.Find(context.TODO(), bson.M{"status": status}, opts)
+	if err != nil {
+		http.Error(w, `{"error":"Failed to fetch verification requests"}`, http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(context.TODO())
+
+	requests := []models.FarmVerification{}
+	if err := cursor.All(context.TODO(), &requests); err != nil {
+		http.Error(w, `{"error":"Error processing verification requests"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(requests)
+}
+
+// ReviewVerification approves or rejects a farm verification request.
+//
+// Endpoint: PUT /api/v1/admin/verifications/:id
+//
+// Body: { "status": "approved"|"rejected", "reviewNotes": "...",
+//
+//	"badges": [ { "type": "organic", "expiresAt": "2027-01-01T00:00:00Z" } ] }
+//
+// Approving marks the farm verified and replaces its badges with the ones given.
+func ReviewVerification(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	objID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid verification ID format"}`, http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Status      string `json:"status"`
+		ReviewNotes string `json:"reviewNotes,omitempty"`
+		Badges      []struct {
+			Type      string     `json:"type"`
+			ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+		} `json:"badges,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"error":"Invalid JSON payload"}`, http.StatusBadRequest)
+		return
+	}
+
+	payload.Status = strings.TrimSpace(payload.Status)
+	if payload.Status != "approved" && payload.Status != "rejected" {
+		http.Error(w, `{"error":"status must be approved or rejected"}`, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now().UTC()
+	badges := []models.FarmBadge{}
+	for _, b := range payload.Badges {
+		if !models.ValidFarmBadges[b.Type] {
+			http.Error(w, `{"error":"Unknown badge type"}`, http.StatusBadRequest)
+			return
+		}
+		if b.ExpiresAt != nil && !b.ExpiresAt.After(now) {
+			http.Error(w, `{"error":"Badge expiry must be in the future"}`, http.StatusBadRequest)
+			return
+		}
+		badges = append(badges, models.FarmBadge{Type: b.Type, GrantedAt: now, ExpiresAt: b.ExpiresAt})
+	}
+
+	reviewer, _ := r.Context().Value(globals.UserIDKey).(string)
+
+	var request models.FarmVerification
+	filter := bson.M{"_id": objID, "status": "pending"}
+	update := bson.M{"$set": bson.M{
+		"status":      payload.Status,
+		"reviewedBy":  reviewer,
+		"reviewNotes": strings.TrimSpace(payload.ReviewNotes),
+		"updatedAt":   now,
+	}}
+	if err := db.FarmVerificationsCollection.FindOneAndUpdate(context.TODO(), filter, update).Decode(&request); err != nil {
+		http.Error(w, `{"error":"Pending verification request not found"}`, http.StatusNotFound)
+		return
+	}
+
+	if payload.Status == "approved" {
+		_, err = db.FarmsCollection.UpdateOne(context.TODO(), bson.M{"_id": request.FarmID}, bson.M{"$set": bson.M{
+			"verified":   true,
+			"verifiedAt": now,
+			"badges":     badges,
+			"updatedAt":  now,
+		}})
+		if err != nil {
+			http.Error(w, `{"error":"Failed to update farm"}`, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Verification " + payload.Status})
+}
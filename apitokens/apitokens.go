@@ -0,0 +1,158 @@
+// Package apitokens lets users manage personal API tokens for scripts and
+// integrations such as farm POS systems. A token acts as its owner, limited
+// to the scopes it was created with; middleware.Authenticate checks them.
+package apitokens
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/middleware"
+	"naevis/models"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	maxTokensPerUser = 20
+	maxNameLength    = 64
+	maxExpiryDays    = 365
+)
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return middleware.APITokenPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// GET /api/v1/tokens
+//
+// The caller's API tokens, newest first. The tokens themselves are never
+// shown again after creation.
+func GetTokens(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := db.APITokensCollection.Find(r.Context(), bson.M{"userid": utils.GetUserIDFromRequest(r)}, opts)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to load tokens"})
+		return
+	}
+	tokens := []models.APIToken{}
+	if err := cursor.All(r.Context(), &tokens); err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to load tokens"})
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "tokens": tokens, "scopes": middleware.Scopes})
+}
+
+// POST /api/v1/tokens
+//
+// Body: { "name": "POS sync", "scopes": ["crops:write"], "expiresInDays": 90 }.
+// expiresInDays may be left out for a token that never expires. The reply
+// holds the token; it can't be retrieved later.
+func CreateToken(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expiresInDays"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid input"})
+		return
+	}
+	input.Name = strings.TrimSpace(input.Name)
+	if input.Name == "" || len(input.Name) > maxNameLength {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Name is required (up to 64 characters)"})
+		return
+	}
+	if len(input.Scopes) == 0 {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "At least one scope is required"})
+		return
+	}
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, s := range input.Scopes {
+		if !middleware.IsScope(s) {
+			utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Unknown scope " + s})
+			return
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	if input.ExpiresInDays < 0 || input.ExpiresInDays > maxExpiryDays {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "expiresInDays must be at most 365"})
+		return
+	}
+
+	ctx := r.Context()
+	userID := utils.GetUserIDFromRequest(r)
+	n, err := db.APITokensCollection.CountDocuments(ctx, bson.M{"userid": userID})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to create token"})
+		return
+	}
+	if n >= maxTokensPerUser {
+		utils.RespondWithJSON(w, http.StatusConflict, utils.M{"success": false, "message": "Token limit reached; delete one first"})
+		return
+	}
+
+	raw, err := newToken()
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to create token"})
+		return
+	}
+	now := time.Now()
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      input.Name,
+		Prefix:    raw[:len(middleware.APITokenPrefix)+4],
+		Hash:      middleware.HashAPIToken(raw),
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if input.ExpiresInDays > 0 {
+		expires := now.AddDate(0, 0, input.ExpiresInDays)
+		token.ExpiresAt = &expires
+	}
+	res, err := db.APITokensCollection.InsertOne(ctx, token)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to create token"})
+		return
+	}
+	token.ID = res.InsertedID.(primitive.ObjectID)
+
+	utils.RespondWithJSON(w, http.StatusCreated, utils.M{"success": true, "token": raw, "details": token})
+}
+
+// DELETE /api/v1/tokens/:id
+//
+// Revokes one of the caller's tokens at once.
+func DeleteToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid token ID"})
+		return
+	}
+	res, err := db.APITokensCollection.DeleteOne(r.Context(), bson.M{"_id": id, "userid": utils.GetUserIDFromRequest(r)})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to delete token"})
+		return
+	}
+	if res.DeletedCount == 0 {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Token not found"})
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+}
@@ -0,0 +1,20 @@
+package apitokens
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureAPITokenIndexes creates the unique index on the token hash that
+// middleware.Authenticate looks tokens up by on every request.
+func EnsureAPITokenIndexes(coll *mongo.Collection) error {
+	indexModel := mongo.IndexModel{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := coll.Indexes().CreateOne(context.Background(), indexModel)
+	return err
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"naevis/mailer"
+	"naevis/rdx"
+	"naevis/structs"
+)
+
+// Failed passwords are counted per username. After loginFreeAttempts the
+// account waits loginBaseDelay before the next try, doubling with each
+// failure; at loginLockAttempts it is locked for loginLockout.
+const (
+	loginFreeAttempts = 3
+	loginLockAttempts = 10
+	loginBaseDelay    = 2 * time.Second
+	loginLockout      = 15 * time.Minute // also the longest delay
+	loginFailWindow   = 24 * time.Hour   // failures older than this are forgotten
+)
+
+func loginFailKey(username string) string { return "login-fail:" + username }
+func loginLockKey(username string) string { return "login-lock:" + username }
+
+// loginDelay is how long an account waits after its nth failure in a row.
+func loginDelay(failures int64) time.Duration {
+	if failures >= loginLockAttempts {
+		return loginLockout
+	}
+	if failures < loginFreeAttempts {
+		return 0
+	}
+	d := loginBaseDelay << (failures - loginFreeAttempts)
+	if d > loginLockout {
+		d = loginLockout
+	}
+	return d
+}
+
+// loginLockedFor reports how long username must still wait before its
+// password is checked again.
+func loginLockedFor(ctx context.Context, username string) time.Duration {
+	ttl, err := rdx.Conn.PTTL(ctx, loginLockKey(username)).Result()
+	if err != nil {
+		log.Printf("Lockout check failed for %s: %v", username, err)
+		return 0
+	}
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// recordLoginFailure counts a failed password for username and starts the
+// delay it earns. user is nil when no account has that name; the counter
+// runs all the same so lockouts don't reveal which names exist.
+func recordLoginFailure(ctx context.Context, username string, user *structs.User) {
+	n, err := rdx.Conn.Incr(ctx, loginFailKey(username)).Result()
+	if err != nil {
+		log.Printf("Failed to count login failure for %s: %v", username, err)
+		return
+	}
+	if n == 1 {
+		rdx.Conn.Expire(ctx, loginFailKey(username), loginFailWindow)
+	}
+
+	delay := loginDelay(n)
+	if delay == 0 {
+		return
+	}
+	if err := rdx.SetWithExpiry(loginLockKey(username), "1", delay); err != nil {
+		log.Printf("Failed to lock %s: %v", username, err)
+	}
+
+	if n == loginLockAttempts && user != nil {
+		body := fmt.Sprintf("There were %d failed attempts to sign in to your account %s, so sign-in is paused for %d minutes.\n\n"+
+			"If this wasn't you, consider changing your password and turning on two-factor authentication.",
+			n, user.Username, int(loginLockout.Minutes()))
+		go func(email string) {
+			if err := mailer.Send(email, "Sign-in to your account is paused", body); err != nil {
+				log.Printf("Failed to send lockout notice to %s: %v", email, err)
+			}
+		}(user.Email)
+	}
+}
+
+// clearLoginFailures forgets username's failures once its password is right.
+func clearLoginFailures(username string) {
+	rdx.RdxDel(loginFailKey(username))
+	rdx.RdxDel(loginLockKey(username))
+}
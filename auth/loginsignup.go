@@ -9,9 +9,12 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"naevis/db"
+	"naevis/jwtkeys"
 	"naevis/middleware"
 	"naevis/mq"
 	"naevis/rdx"
@@ -29,97 +32,185 @@ const (
 	accessTokenTTL  = 15 * time.Minute   // 15 minutes
 )
 
-var (
-	// tokenSigningAlgo = jwt.SigningMethodHS256
-	jwtSecret = []byte("your_secret_key") // Replace with a secure secret key
-)
+// issueAccessToken signs a short-lived access token for user in sessionID.
+func issueAccessToken(user structs.User, sessionID string) (string, error) {
+	claims := &middleware.Claims{
+		Username:  user.Username,
+		UserID:    user.UserID,
+		Role:      user.Role, // assumes Role []string exists in your structs.User
+		SessionID: sessionID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(accessTokenTTL)),
+		},
+	}
+
+	return jwtkeys.Sign(claims)
+}
 
 func loginHandler(w http.ResponseWriter, r *http.Request) {
-	var user structs.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var req struct {
+		structs.User
+		Password string `json:"password"` // structs.User never decodes it
+		DeviceID string `json:"deviceId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	user := req.User
+	ctx := r.Context()
+
+	// A locked account's password isn't even checked
+	if wait := loginLockedFor(ctx, user.Username); wait > 0 {
+		logLoginAttempt(ctx, r, user.Username, nil, req.DeviceID, false, "locked")
+		seconds := int(wait.Round(time.Second).Seconds())
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		http.Error(w, "Too many failed attempts, try again in "+strconv.Itoa(seconds)+" seconds", http.StatusTooManyRequests)
+		return
+	}
 
 	var storedUser structs.User
 	err := db.UserCollection.FindOne(context.TODO(), bson.M{"username": user.Username}).Decode(&storedUser)
 	if err != nil {
+		recordLoginFailure(ctx, user.Username, nil)
+		logLoginAttempt(ctx, r, user.Username, nil, req.DeviceID, false, "unknown-user")
 		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
 
-	// // Check if verified
-	// if !storedUser.EmailVerified {
-	// 	http.Error(w, "User not verified. Please check your email for the OTP.", http.StatusUnauthorized)
-	// 	return
-	// }
-
 	// Check password
-	if err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(user.Password)); err != nil {
+	if err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(req.Password)); err != nil {
+		recordLoginFailure(ctx, user.Username, &storedUser)
+		logLoginAttempt(ctx, r, user.Username, &storedUser, req.DeviceID, false, "bad-password")
 		http.Error(w, "Invalid username or password", http.StatusUnauthorized)
 		return
 	}
+	clearLoginFailures(user.Username)
 
-	// Generate JWT
-	claims := &middleware.Claims{
-		Username: storedUser.Username,
-		UserID:   storedUser.UserID,
-		Role:     storedUser.Role, // assumes Role []string exists in your structs.User
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(12 * time.Hour)),
-		},
+	// Only told once the password is right, so it can't probe for accounts
+	if requireEmailVerification() && !storedUser.EmailVerified {
+		logLoginAttempt(ctx, r, user.Username, &storedUser, req.DeviceID, false, "unverified")
+		http.Error(w, "User not verified. Please check your email for the OTP.", http.StatusForbidden)
+		return
+	}
+
+	continueLogin(w, r, storedUser, req.DeviceID)
+}
+
+// continueLogin takes a user whose first factor (password or provider) has
+// been checked through suspension and, if enabled, the 2FA step.
+func continueLogin(w http.ResponseWriter, r *http.Request, storedUser structs.User, deviceID string) {
+	if storedUser.SuspendedUntil != nil && storedUser.SuspendedUntil.After(time.Now()) {
+		logLoginAttempt(r.Context(), r, storedUser.Username, &storedUser, deviceID, false, "suspended")
+		http.Error(w, "Account suspended until "+storedUser.SuspendedUntil.Format(time.RFC1123), http.StatusForbidden)
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	// With 2FA on, the first factor only earns a pending token for the code step
+	if storedUser.MFAEnabled {
+		mfaToken, err := startMFALogin(r.Context(), storedUser.UserID, deviceID)
+		if err != nil {
+			http.Error(w, "Failed to start two-factor login", http.StatusInternalServerError)
+			return
+		}
+		utils.SendResponse(w, http.StatusOK, map[string]any{
+			"mfaRequired": true,
+			"mfaToken":    mfaToken,
+		}, "Two-factor code required", nil)
+		return
+	}
+
+	completeLogin(w, r, storedUser, deviceID)
+}
+
+// completeLogin starts a session for user on deviceID and replies with its
+// tokens. It runs once every login check has passed.
+func completeLogin(w http.ResponseWriter, r *http.Request, storedUser structs.User, deviceID string) {
+	// Each device gets its own session and refresh token
+	var err error
+	generated := deviceID == ""
+	if generated {
+		if deviceID, err = newDeviceID(); err != nil {
+			http.Error(w, "Error generating refresh token", http.StatusInternalServerError)
+			return
+		}
+	}
+	sessionID, refreshToken, err := startSession(r.Context(), r, storedUser.UserID, deviceID)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		http.Error(w, "Failed to store refresh token", http.StatusInternalServerError)
 		return
 	}
 
-	// Generate refresh token
-	refreshToken, err := generateRefreshToken()
+	notifyIfNewLogin(r.Context(), r, storedUser, deviceID, sessionID, generated)
+	logLoginAttempt(r.Context(), r, storedUser.Username, &storedUser, deviceID, true, "")
+
+	// Generate JWT
+	tokenString, err := issueAccessToken(storedUser, sessionID)
 	if err != nil {
-		http.Error(w, "Error generating refresh token", http.StatusInternalServerError)
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
 		return
 	}
-	hashedRefresh := hashToken(refreshToken)
 
+	// Refresh tokens used to live on the user document; drop the old fields.
 	_, err = db.UserCollection.UpdateOne(
 		context.TODO(),
 		bson.M{"userid": storedUser.UserID},
-		bson.M{"$set": bson.M{
-			"refresh_token":  hashedRefresh,
-			"refresh_expiry": time.Now().Add(refreshTokenTTL),
-			"last_login":     time.Now(),
-		}},
+		bson.M{
+			"$set":   bson.M{"last_login": time.Now()},
+			"$unset": bson.M{"refresh_token": "", "refresh_expiry": ""},
+		},
 	)
 	if err != nil {
-		http.Error(w, "Failed to store refresh token", http.StatusInternalServerError)
-		return
+		log.Printf("Failed to record login for %s: %v", storedUser.UserID, err)
 	}
 
 	// Return tokens
 	utils.SendResponse(w, http.StatusOK, map[string]string{
 		"token":        tokenString,
 		"refreshToken": refreshToken,
+		"deviceId":     deviceID,
 		"userid":       storedUser.UserID,
 	}, "Login successful", nil)
 }
 
 func registerHandler(w http.ResponseWriter, r *http.Request) {
-	var user structs.User
-	if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+	var req struct {
+		structs.User
+		Password string `json:"password"` // structs.User never decodes it
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	user := req.User
+	if len(req.Password) < minPasswordLength {
+		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+		return
+	}
+
+	user.Email = strings.TrimSpace(user.Email)
+	if user.Email == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
 
 	log.Printf("Registering user: %s", user.Username)
 
-	// Check if user already exists
+	// Check if user already exists. Email is unique too: verification and
+	// password resets look accounts up by it.
 	var existingUser structs.User
-	err := db.UserCollection.FindOne(context.TODO(), bson.M{"username": user.Username}).Decode(&existingUser)
+	err := db.UserCollection.FindOne(context.TODO(), bson.M{"$or": []bson.M{
+		{"username": user.Username},
+		{"email": user.Email},
+	}}).Decode(&existingUser)
 	if err == nil {
+		if existingUser.Email == user.Email {
+			http.Error(w, "An account with this email already exists", http.StatusConflict)
+			return
+		}
 		http.Error(w, "User already exists", http.StatusConflict)
 		return
 	} else if err != mongo.ErrNoDocuments {
@@ -128,7 +219,7 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Hash password
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		log.Printf("Failed to hash password for user %s: %v", user.Username, err)
 		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
@@ -136,24 +227,8 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	user.Password = string(hashedPassword)
 	user.UserID = "u" + utils.GenerateName(10)
-	// user.EmailVerified = false
-	user.EmailVerified = true
-	user.Role = []string{"user"}
-
-	// // Generate OTP and send email
-	// otp := generateOTP(6)
-	// err = sendEmailOTP(user.Email, otp)
-	// if err != nil {
-	// 	log.Printf("Failed to send OTP email: %v", err)
-	// 	http.Error(w, "Failed to send OTP", http.StatusInternalServerError)
-	// 	return
-	// }
-
-	// // Store OTP in Redis (expires in 10 minutes)
-	// err = rdx.SetWithExpiry("otp:"+user.Email, otp, 10*time.Minute)
-	// if err != nil {
-	// 	log.Printf("Failed to cache OTP: %v", err)
-	// }
+	user.EmailVerified = false
+	user.Role = []string{middleware.RoleBuyer}
 
 	err = rdx.RdxSet(fmt.Sprintf("users:%s", user.UserID), user.Username)
 	if err != nil {
@@ -167,6 +242,12 @@ func registerHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Send the verification code; if this fails the user can ask for
+	// another through request-otp
+	if err := sendOTP(r.Context(), user.Email); err != nil {
+		log.Printf("Failed to send OTP email to %s: %v", user.Email, err)
+	}
+
 	// Optional: Emit to MQ, cache user ID, etc.
 
 	w.WriteHeader(http.StatusCreated)
@@ -191,19 +272,27 @@ func logoutUserHandler(w http.ResponseWriter, r *http.Request) {
 	// Extract the token and invalidate it in Redis
 	tokenString = tokenString[7:]
 	claims := &middleware.Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return jwtSecret, nil
-	})
+	token, err := jwtkeys.Parse(tokenString, claims)
 
 	if err != nil || !token.Valid {
 		http.Error(w, "Invalid token", http.StatusUnauthorized)
 		return
 	}
 
-	// Remove token from Redis cache
-	_, err = rdx.RdxHdel("tokki", claims.UserID)
+	// End the session; tokens issued before sessions existed carry no sid,
+	// so fall back to the refresh token sent along, if any
+	if claims.SessionID != "" {
+		err = revokeSession(r.Context(), claims.SessionID)
+	} else {
+		var body struct {
+			RefreshToken string `json:"refreshToken"`
+		}
+		if json.NewDecoder(r.Body).Decode(&body) == nil && body.RefreshToken != "" {
+			err = revokeRefreshToken(r.Context(), body.RefreshToken)
+		}
+	}
 	if err != nil {
-		log.Printf("Error removing token from Redis: %v", err)
+		log.Printf("Error revoking session: %v", err)
 		http.Error(w, "Failed to log out", http.StatusInternalServerError)
 		return
 	}
@@ -214,51 +303,6 @@ func logoutUserHandler(w http.ResponseWriter, r *http.Request) {
 	utils.SendResponse(w, http.StatusOK, nil, "User logged out successfully", nil)
 }
 
-func refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
-	tokenString := r.Header.Get("Authorization")
-	if tokenString == "" {
-		http.Error(w, "Missing token", http.StatusUnauthorized)
-		return
-	}
-
-	if len(tokenString) < 7 || tokenString[:7] != "Bearer " {
-		http.Error(w, "Invalid token format", http.StatusUnauthorized)
-		return
-	}
-
-	tokenString = tokenString[7:]
-	claims := &middleware.Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (any, error) {
-		return jwtSecret, nil
-	})
-
-	if err != nil || !token.Valid {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
-		return
-	}
-
-	// Ensure the token is not expired and refresh it
-	if time.Until(claims.ExpiresAt.Time) < 30*time.Minute {
-		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(72 * time.Hour)) // Extend the expiration
-		newToken := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		newTokenString, err := newToken.SignedString(jwtSecret)
-		if err != nil {
-			http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
-			return
-		}
-
-		// Update the token in Redis
-		err = rdx.RdxHset("tokki", claims.UserID, newTokenString)
-		if err != nil {
-			log.Printf("Error updating token in Redis: %v", err)
-		}
-
-		utils.SendResponse(w, http.StatusOK, map[string]string{"token": newTokenString}, "Token refreshed successfully", nil)
-	} else {
-		http.Error(w, "Token refresh not allowed yet", http.StatusForbidden)
-	}
-}
-
 // Generates a random refresh token
 func generateRefreshToken() (string, error) {
 	tokenBytes := make([]byte, 32)
@@ -0,0 +1,317 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/rdx"
+	"naevis/structs"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	mfaPendingTTL     = 5 * time.Minute // time allowed for the code step of a login
+	mfaMaxAttempts    = 5               // wrong codes per pending login
+	mfaUserAttempts   = 10              // wrong codes per user per mfaAttemptWindow on enable/disable
+	mfaAttemptWindow  = 15 * time.Minute
+	recoveryCodeCount = 10
+)
+
+func mfaPendingKey(hash string) string   { return "mfa-pending:" + hash }
+func mfaAttemptsKey(scope string) string { return "mfa-attempts:" + scope }
+
+func mfaIssuer() string {
+	if issuer := os.Getenv("MFA_ISSUER"); issuer != "" {
+		return issuer
+	}
+	return "Farmium"
+}
+
+// startMFALogin parks a password-verified login until the second factor is
+// given, returning the opaque token the client sends back with the code.
+// It is not an access token and no other endpoint accepts it.
+func startMFALogin(ctx context.Context, userID, deviceID string) (string, error) {
+	token, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	if err := rdx.SetWithExpiry(mfaPendingKey(hashToken(token)), userID+"|"+deviceID, mfaPendingTTL); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// normalizeRecoveryCode lets "ABCDE-12345" and "abcde12345" match.
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// newRecoveryCodes returns fresh codes for the user and their hashes for
+// storage.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := strings.ToLower(b32.EncodeToString(b))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+	return codes, hashes, nil
+}
+
+// verifyMFACode accepts a current TOTP code or an unused recovery code,
+// spending whichever it was.
+func verifyMFACode(ctx context.Context, user structs.User, code string) (bool, error) {
+	if step, ok := checkTOTP(user.MFASecret, code, time.Now(), user.MFALastStep); ok {
+		res, err := db.UserCollection.UpdateOne(ctx,
+			bson.M{"userid": user.UserID, "mfa_last_step": bson.M{"$not": bson.M{"$gte": step}}},
+			bson.M{"$set": bson.M{"mfa_last_step": step}},
+		)
+		if err != nil {
+			return false, err
+		}
+		return res.ModifiedCount == 1, nil
+	}
+
+	hash := hashToken(normalizeRecoveryCode(code))
+	res, err := db.UserCollection.UpdateOne(ctx,
+		bson.M{"userid": user.UserID, "mfa_recovery_codes": hash},
+		bson.M{"$pull": bson.M{"mfa_recovery_codes": hash}},
+	)
+	if err != nil {
+		return false, err
+	}
+	if res.ModifiedCount == 1 {
+		log.Printf("Recovery code used by %s; %d left", user.UserID, len(user.RecoveryCodes)-1)
+		return true, nil
+	}
+	return false, nil
+}
+
+// POST /api/v1/auth/login/mfa
+//
+// Body: { "mfaToken": "...", "code": "123456" }. The second login step for
+// accounts with 2FA; code may also be a recovery code.
+func LoginMFA(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		MFAToken string `json:"mfaToken"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.MFAToken == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	hash := hashToken(input.MFAToken)
+
+	pending, err := rdx.RdxGet(mfaPendingKey(hash))
+	userID, deviceID, found := strings.Cut(pending, "|")
+	if err != nil || !found {
+		http.Error(w, "Login expired, please sign in again", http.StatusUnauthorized)
+		return
+	}
+	if overLimit(ctx, mfaAttemptsKey(hash), mfaMaxAttempts, mfaPendingTTL) {
+		rdx.RdxDel(mfaPendingKey(hash))
+		http.Error(w, "Too many attempts, please sign in again", http.StatusTooManyRequests)
+		return
+	}
+
+	var user structs.User
+	if err := db.UserCollection.FindOne(ctx, bson.M{"userid": userID}).Decode(&user); err != nil {
+		http.Error(w, "Login expired, please sign in again", http.StatusUnauthorized)
+		return
+	}
+	ok, err := verifyMFACode(ctx, user, input.Code)
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		logLoginAttempt(ctx, r, user.Username, &user, deviceID, false, "bad-mfa-code")
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	// The pending token is single use
+	if n, err := rdx.Conn.Del(ctx, mfaPendingKey(hash)).Result(); err != nil || n == 0 {
+		http.Error(w, "Login expired, please sign in again", http.StatusUnauthorized)
+		return
+	}
+	rdx.RdxDel(mfaAttemptsKey(hash))
+
+	completeLogin(w, r, user, deviceID)
+}
+
+// POST /api/v1/auth/mfa/setup
+//
+// Starts enrolment: returns a new secret and its otpauth:// URI for a QR
+// code. 2FA stays off until EnableMFA confirms a code from the app.
+func SetupMFA(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var user structs.User
+	if err := db.UserCollection.FindOne(r.Context(), bson.M{"userid": utils.GetUserIDFromRequest(r)}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.MFAEnabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		http.Error(w, "Failed to create secret", http.StatusInternalServerError)
+		return
+	}
+	_, err = db.UserCollection.UpdateOne(r.Context(),
+		bson.M{"userid": user.UserID},
+		bson.M{"$set": bson.M{"mfa_pending_secret": secret}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to save secret", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{
+		"secret": secret,
+		"uri":    totpURI(mfaIssuer(), user.Username, secret),
+	})
+}
+
+// POST /api/v1/auth/mfa/enable
+//
+// Body: { "code": "123456" }. Turns 2FA on once the app's code matches the
+// pending secret, and returns the recovery codes. They are shown only once.
+func EnableMFA(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		Code string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	userID := utils.GetUserIDFromRequest(r)
+
+	var user structs.User
+	if err := db.UserCollection.FindOne(ctx, bson.M{"userid": userID}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if user.MFAEnabled {
+		http.Error(w, "Two-factor authentication is already enabled", http.StatusConflict)
+		return
+	}
+	if user.MFAPending == "" {
+		http.Error(w, "Start setup first", http.StatusBadRequest)
+		return
+	}
+	if overLimit(ctx, mfaAttemptsKey("user:"+userID), mfaUserAttempts, mfaAttemptWindow) {
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	step, ok := checkTOTP(user.MFAPending, input.Code, time.Now(), 0)
+	if !ok {
+		http.Error(w, "Invalid code", http.StatusUnauthorized)
+		return
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		http.Error(w, "Failed to create recovery codes", http.StatusInternalServerError)
+		return
+	}
+	_, err = db.UserCollection.UpdateOne(ctx,
+		bson.M{"userid": userID},
+		bson.M{
+			"$set": bson.M{
+				"mfa_enabled":        true,
+				"mfa_secret":         user.MFAPending,
+				"mfa_last_step":      step,
+				"mfa_recovery_codes": hashes,
+			},
+			"$unset": bson.M{"mfa_pending_secret": ""},
+		},
+	)
+	if err != nil {
+		http.Error(w, "Failed to enable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	rdx.RdxDel(mfaAttemptsKey("user:" + userID))
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"enabled": true, "recoveryCodes": codes})
+}
+
+// POST /api/v1/auth/mfa/disable
+//
+// Body: { "password": "...", "code": "123456" }. Both are required; the
+// code may be a recovery code.
+func DisableMFA(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	userID := utils.GetUserIDFromRequest(r)
+
+	var user structs.User
+	if err := db.UserCollection.FindOne(ctx, bson.M{"userid": userID}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if !user.MFAEnabled {
+		http.Error(w, "Two-factor authentication is not enabled", http.StatusConflict)
+		return
+	}
+	if overLimit(ctx, mfaAttemptsKey("user:"+userID), mfaUserAttempts, mfaAttemptWindow) {
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+		http.Error(w, "Invalid password or code", http.StatusUnauthorized)
+		return
+	}
+	ok, err := verifyMFACode(ctx, user, input.Code)
+	if err != nil {
+		http.Error(w, "Failed to verify code", http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, "Invalid password or code", http.StatusUnauthorized)
+		return
+	}
+
+	_, err = db.UserCollection.UpdateOne(ctx,
+		bson.M{"userid": userID},
+		bson.M{
+			"$set":   bson.M{"mfa_enabled": false},
+			"$unset": bson.M{"mfa_secret": "", "mfa_last_step": "", "mfa_recovery_codes": "", "mfa_pending_secret": ""},
+		},
+	)
+	if err != nil {
+		http.Error(w, "Failed to disable two-factor authentication", http.StatusInternalServerError)
+		return
+	}
+	rdx.RdxDel(mfaAttemptsKey("user:" + userID))
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"enabled": false})
+}
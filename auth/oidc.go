@@ -0,0 +1,376 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/middleware"
+	"naevis/oidc"
+	"naevis/rdx"
+	"naevis/structs"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const oidcStateTTL = 10 * time.Minute // time allowed at the provider's sign-in page
+
+func oidcStateKey(hash string) string { return "oidc-state:" + hash }
+
+// oidcPending is what a state value stands for until the provider sends
+// the user back. UserID is set when an account is linking rather than
+// signing in.
+type oidcPending struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"`
+	Nonce    string `json:"nonce"`
+	UserID   string `json:"userid,omitempty"`
+}
+
+var usernameUnsafe = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// startOIDC parks a new flow and returns the provider URL to send the
+// user to, with the state that comes back.
+func startOIDC(ctx context.Context, p *oidc.Provider, userID string) (string, string, error) {
+	state, err := oidc.RandomString(24)
+	if err != nil {
+		return "", "", err
+	}
+	pending := oidcPending{Provider: p.Name, UserID: userID}
+	if pending.Verifier, err = oidc.RandomString(32); err != nil {
+		return "", "", err
+	}
+	if pending.Nonce, err = oidc.RandomString(16); err != nil {
+		return "", "", err
+	}
+
+	authURL, err := p.AuthURL(ctx, state, pending.Nonce, pending.Verifier)
+	if err != nil {
+		return "", "", err
+	}
+	raw, _ := json.Marshal(pending)
+	if err := rdx.SetWithExpiry(oidcStateKey(hashToken(state)), string(raw), oidcStateTTL); err != nil {
+		return "", "", err
+	}
+	return authURL, state, nil
+}
+
+// finishOIDC spends state and exchanges code for the identity it was
+// issued to. The state must have been started for provider and userID.
+func finishOIDC(ctx context.Context, provider, userID, code, state string) (*oidc.Identity, error) {
+	raw, err := rdx.Conn.GetDel(ctx, oidcStateKey(hashToken(state))).Result()
+	if err != nil {
+		return nil, errors.New("sign-in expired, please start again")
+	}
+	var pending oidcPending
+	if err := json.Unmarshal([]byte(raw), &pending); err != nil || pending.Provider != provider || pending.UserID != userID {
+		return nil, errors.New("sign-in expired, please start again")
+	}
+	p, err := oidc.Get(provider)
+	if err != nil {
+		return nil, err
+	}
+	return p.Exchange(ctx, code, pending.Verifier, pending.Nonce)
+}
+
+func identityFilter(provider, subject string) bson.M {
+	return bson.M{"identities": bson.M{"$elemMatch": bson.M{"provider": provider, "subject": subject}}}
+}
+
+// baseUsername picks a username from what the provider shared.
+func baseUsername(id *oidc.Identity) string {
+	local, _, _ := strings.Cut(id.Email, "@")
+	for _, candidate := range []string{id.PreferredUsername, local, id.Name} {
+		name := strings.Trim(usernameUnsafe.ReplaceAllString(strings.ToLower(candidate), "_"), "_")
+		if len(name) > 20 {
+			name = name[:20]
+		}
+		if len(name) >= 3 {
+			return name
+		}
+	}
+	return "user"
+}
+
+// uniqueUsername returns base, or base with a numeric suffix, whichever is
+// free.
+func uniqueUsername(ctx context.Context, base string) (string, error) {
+	candidate := base
+	for i := 0; i < 10; i++ {
+		n, err := db.UserCollection.CountDocuments(ctx, bson.M{"username": candidate})
+		if err != nil {
+			return "", err
+		}
+		if n == 0 {
+			return candidate, nil
+		}
+		candidate = base + GenerateOTP(4)
+	}
+	return base + "_" + strings.ToLower(utils.GenerateName(8)), nil
+}
+
+// provisionUser creates an account for a first-time provider sign-in. It
+// has no password; the user can set one with a password reset.
+func provisionUser(ctx context.Context, id *oidc.Identity) (structs.User, error) {
+	username, err := uniqueUsername(ctx, baseUsername(id))
+	if err != nil {
+		return structs.User{}, err
+	}
+	now := time.Now()
+	user := structs.User{
+		UserID:        "u" + utils.GenerateName(10),
+		Username:      username,
+		Email:         id.Email,
+		Name:          id.Name,
+		Role:          []string{middleware.RoleBuyer},
+		EmailVerified: id.EmailVerified,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+		Identities: []structs.LinkedIdentity{{
+			Provider: id.Provider,
+			Subject:  id.Subject,
+			Email:    id.Email,
+			LinkedAt: now,
+		}},
+	}
+	if _, err := db.UserCollection.InsertOne(ctx, user); err != nil {
+		return structs.User{}, err
+	}
+	if err := rdx.RdxSet(fmt.Sprintf("users:%s", user.UserID), user.Username); err != nil {
+		log.Printf("Failed to cache username: %v", err)
+	}
+	log.Printf("Provisioned user %s from %s", user.Username, id.Provider)
+	return user, nil
+}
+
+// GET /api/v1/auth/oidc
+//
+// The providers users can sign in with.
+func OIDCProviders(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"providers": oidc.Names()})
+}
+
+// POST /api/v1/auth/oidc/:provider/start
+//
+// Begins a sign-in. The client sends the user to authUrl; the provider
+// returns them to the web app's callback page with a code and state.
+func StartOIDCLogin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	p, err := oidc.Get(ps.ByName("provider"))
+	if err != nil {
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+	authURL, state, err := startOIDC(r.Context(), p, "")
+	if err != nil {
+		log.Printf("Failed to start %s sign-in: %v", p.Name, err)
+		http.Error(w, "Failed to start sign-in", http.StatusBadGateway)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"authUrl": authURL, "state": state})
+}
+
+// POST /api/v1/auth/oidc/:provider/callback
+//
+// Body: { "code": "...", "state": "...", "deviceId": "..." }. Signs in the
+// account linked to the provider identity, creating one on first use. The
+// reply is the same as a password login's.
+func OIDCCallback(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input struct {
+		Code     string `json:"code"`
+		State    string `json:"state"`
+		DeviceID string `json:"deviceId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Code == "" || input.State == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	provider := ps.ByName("provider")
+
+	id, err := finishOIDC(ctx, provider, "", input.Code, input.State)
+	if err != nil {
+		log.Printf("%s sign-in failed: %v", provider, err)
+		http.Error(w, "Sign-in with "+provider+" failed", http.StatusUnauthorized)
+		return
+	}
+
+	var user structs.User
+	err = db.UserCollection.FindOne(ctx, identityFilter(id.Provider, id.Subject)).Decode(&user)
+	if err == mongo.ErrNoDocuments {
+		// Never attach a provider to an existing account by email alone;
+		// the owner links it after signing in.
+		if id.Email != "" {
+			n, err := db.UserCollection.CountDocuments(ctx, bson.M{"email": id.Email})
+			if err != nil {
+				http.Error(w, "Internal server error", http.StatusInternalServerError)
+				return
+			}
+			if n > 0 {
+				http.Error(w, "An account with this email already exists. Sign in and link "+provider+" from your profile.", http.StatusConflict)
+				return
+			}
+		}
+		if requireEmailVerification() && (id.Email == "" || !id.EmailVerified) {
+			http.Error(w, provider+" did not share a verified email address", http.StatusForbidden)
+			return
+		}
+		user, err = provisionUser(ctx, id)
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	continueLogin(w, r, user, input.DeviceID)
+}
+
+// GET /api/v1/profile/identities
+//
+// The providers linked to the caller's account.
+func GetIdentities(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var user structs.User
+	if err := db.UserCollection.FindOne(r.Context(), bson.M{"userid": utils.GetUserIDFromRequest(r)}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	identities := user.Identities
+	if identities == nil {
+		identities = []structs.LinkedIdentity{}
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"identities":  identities,
+		"hasPassword": user.Password != "",
+	})
+}
+
+// POST /api/v1/profile/identities/:provider/start
+//
+// Begins linking a provider to the caller's account; finish with
+// LinkIdentity.
+func StartLinkIdentity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	p, err := oidc.Get(ps.ByName("provider"))
+	if err != nil {
+		http.Error(w, "Unknown provider", http.StatusNotFound)
+		return
+	}
+	authURL, state, err := startOIDC(r.Context(), p, utils.GetUserIDFromRequest(r))
+	if err != nil {
+		log.Printf("Failed to start %s link: %v", p.Name, err)
+		http.Error(w, "Failed to start linking", http.StatusBadGateway)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]string{"authUrl": authURL, "state": state})
+}
+
+// POST /api/v1/profile/identities/:provider
+//
+// Body: { "code": "...", "state": "..." }. Links the provider identity to
+// the caller's account.
+func LinkIdentity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input struct {
+		Code  string `json:"code"`
+		State string `json:"state"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Code == "" || input.State == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+	userID := utils.GetUserIDFromRequest(r)
+	provider := ps.ByName("provider")
+
+	id, err := finishOIDC(ctx, provider, userID, input.Code, input.State)
+	if err != nil {
+		log.Printf("%s link for %s failed: %v", provider, userID, err)
+		http.Error(w, "Linking "+provider+" failed", http.StatusUnauthorized)
+		return
+	}
+
+	var owner structs.User
+	err = db.UserCollection.FindOne(ctx, identityFilter(id.Provider, id.Subject)).Decode(&owner)
+	if err == nil {
+		if owner.UserID == userID {
+			http.Error(w, "This "+provider+" account is already linked", http.StatusConflict)
+		} else {
+			http.Error(w, "This "+provider+" account is linked to another user", http.StatusConflict)
+		}
+		return
+	} else if err != mongo.ErrNoDocuments {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	linked := structs.LinkedIdentity{Provider: id.Provider, Subject: id.Subject, Email: id.Email, LinkedAt: time.Now()}
+	res, err := db.UserCollection.UpdateOne(ctx,
+		bson.M{"userid": userID, "identities.provider": bson.M{"$ne": id.Provider}},
+		bson.M{"$push": bson.M{"identities": linked}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to link account", http.StatusInternalServerError)
+		return
+	}
+	if res.MatchedCount == 0 {
+		http.Error(w, "A "+provider+" account is already linked; unlink it first", http.StatusConflict)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"identity": linked})
+}
+
+// DELETE /api/v1/profile/identities/:provider
+//
+// Unlinks a provider. The last way to sign in can't be removed: users
+// without a password must keep one provider.
+func UnlinkIdentity(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := r.Context()
+	userID := utils.GetUserIDFromRequest(r)
+	provider := ps.ByName("provider")
+
+	var user structs.User
+	if err := db.UserCollection.FindOne(ctx, bson.M{"userid": userID}).Decode(&user); err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	found := false
+	for _, id := range user.Identities {
+		if id.Provider == provider {
+			found = true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, "Provider not linked", http.StatusNotFound)
+		return
+	}
+	if user.Password == "" && len(user.Identities) == 1 {
+		http.Error(w, "Set a password before unlinking your only sign-in method", http.StatusConflict)
+		return
+	}
+
+	filter := bson.M{"userid": userID}
+	if user.Password == "" {
+		filter["identities.1"] = bson.M{"$exists": true} // still true when the pull lands
+	}
+	res, err := db.UserCollection.UpdateOne(ctx, filter,
+		bson.M{"$pull": bson.M{"identities": bson.M{"provider": provider}}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to unlink account", http.StatusInternalServerError)
+		return
+	}
+	if res.MatchedCount == 0 {
+		http.Error(w, "Set a password before unlinking your only sign-in method", http.StatusConflict)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"unlinked": provider})
+}
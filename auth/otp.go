@@ -2,48 +2,156 @@ package auth
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
 	"encoding/json"
-	"math/rand"
-	"naevis/db"
-	"naevis/rdx"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
 	"net/http"
-	"net/smtp"
+	"os"
 	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/mailer"
+	"naevis/rdx"
 
 	"github.com/julienschmidt/httprouter"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
+const (
+	otpLength      = 6
+	otpTTL         = 10 * time.Minute
+	otpCooldown    = time.Minute // between two codes sent to one address
+	otpMaxAttempts = 5           // wrong guesses before the code is discarded
+)
+
+var errOTPCooldown = errors.New("a code was sent recently, please wait before requesting another")
+
+func otpKey(email string) string         { return "otp:" + email }
+func otpAttemptsKey(email string) string { return "otp-attempts:" + email }
+func otpCooldownKey(email string) string { return "otp-cooldown:" + email }
+
+// requireEmailVerification reports whether unverified users are kept from
+// logging in (REQUIRE_EMAIL_VERIFICATION=true).
+func requireEmailVerification() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// GenerateOTP returns a random numeric code of the given length.
 func GenerateOTP(length int) string {
-	digits := "0123456789"
 	var otp strings.Builder
+	ten := big.NewInt(10)
 	for i := 0; i < length; i++ {
-		otp.WriteByte(digits[rand.Intn(len(digits))])
+		n, err := rand.Int(rand.Reader, ten)
+		if err != nil {
+			panic("auth: crypto/rand failed: " + err.Error())
+		}
+		otp.WriteByte(byte('0' + n.Int64()))
 	}
 	return otp.String()
 }
 
+// SendEmailOTP mails a verification code to toEmail.
 func SendEmailOTP(toEmail, otp string) error {
-	from := "youremail@example.com"
-	pass := "your-app-password"
-	smtpHost := "smtp.gmail.com"
-	smtpPort := "587"
+	body := fmt.Sprintf("Your verification code is: %s\n\nIt expires in %d minutes. If you didn't sign up, you can ignore this email.",
+		otp, int(otpTTL.Minutes()))
+	return mailer.Send(toEmail, "Email Verification", body)
+}
 
-	msg := []byte("Subject: Email Verification\n\nYour OTP is: " + otp)
+// sendOTP issues a new code for email, replacing any earlier one, unless a
+// code went out within otpCooldown. Only the code's hash is stored.
+func sendOTP(ctx context.Context, email string) error {
+	ok, err := rdx.Conn.SetNX(ctx, otpCooldownKey(email), 1, otpCooldown).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errOTPCooldown
+	}
 
-	auth := smtp.PlainAuth("", from, pass, smtpHost)
-	return smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{toEmail}, msg)
+	otp := GenerateOTP(otpLength)
+	if err := rdx.SetWithExpiry(otpKey(email), hashToken(otp), otpTTL); err != nil {
+		return err
+	}
+	rdx.RdxDel(otpAttemptsKey(email))
+	return SendEmailOTP(email, otp)
 }
 
+// POST /api/v1/auth/request-otp
+//
+// Body: { "email": "..." }. Sends a fresh code to an unverified account.
+// The reply is the same whether or not the address is registered.
+func RequestOTPHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Email) == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+	email := strings.TrimSpace(input.Email)
+
+	n, err := db.UserCollection.CountDocuments(r.Context(), bson.M{"email": email, "email_verified": false})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if n > 0 {
+		err = sendOTP(r.Context(), email)
+		if errors.Is(err, errOTPCooldown) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			log.Printf("Failed to send OTP to %s: %v", email, err)
+			http.Error(w, "Failed to send OTP", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{"message": "If the address needs verifying, a new code is on its way"})
+}
+
+// POST /api/v1/auth/verify-otp
+//
+// Body: { "email": "...", "otp": "123456" }. A code allows otpMaxAttempts
+// guesses before it is discarded.
 func VerifyOTPHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	var input struct {
 		Email string `json:"email"`
 		OTP   string `json:"otp"`
 	}
-	json.NewDecoder(r.Body).Decode(&input)
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	email := strings.TrimSpace(input.Email)
+
+	storedOTP, err := rdx.RdxGet(otpKey(email))
+	if err != nil || storedOTP == "" {
+		http.Error(w, "Invalid or expired OTP", http.StatusUnauthorized)
+		return
+	}
+
+	attempts, err := rdx.Conn.Incr(r.Context(), otpAttemptsKey(email)).Result()
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if attempts == 1 {
+		rdx.Conn.Expire(r.Context(), otpAttemptsKey(email), otpTTL)
+	}
+	if attempts > otpMaxAttempts {
+		rdx.RdxDel(otpKey(email))
+		http.Error(w, "Too many attempts, request a new code", http.StatusTooManyRequests)
+		return
+	}
 
-	storedOTP, err := rdx.RdxGet("otp:" + input.Email)
-	if err != nil || storedOTP != input.OTP {
+	if subtle.ConstantTimeCompare([]byte(storedOTP), []byte(hashToken(strings.TrimSpace(input.OTP)))) != 1 {
 		http.Error(w, "Invalid or expired OTP", http.StatusUnauthorized)
 		return
 	}
@@ -51,7 +159,7 @@ func VerifyOTPHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 	// Mark user as verified
 	_, err = db.UserCollection.UpdateOne(
 		context.TODO(),
-		bson.M{"email": input.Email},
+		bson.M{"email": email, "email_verified": false},
 		bson.M{"$set": bson.M{"email_verified": true}},
 	)
 	if err != nil {
@@ -59,7 +167,9 @@ func VerifyOTPHandler(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 		return
 	}
 
-	rdx.RdxDel("otp:" + input.Email) // Clean up OTP
+	// Clean up OTP
+	rdx.RdxDel(otpKey(email))
+	rdx.RdxDel(otpAttemptsKey(email))
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "User verified successfully"})
 }
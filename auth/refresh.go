@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/structs"
+	"naevis/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	errInvalidRefresh = errors.New("invalid or expired refresh token")
+	errRefreshReused  = errors.New("refresh token reused")
+)
+
+// newDeviceID names a device that didn't send its own ID at login.
+func newDeviceID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "d" + hex.EncodeToString(b), nil
+}
+
+// issueRefreshToken stores a fresh token in familyID and returns the raw
+// value for the client. Only its hash is kept. The family ID is the ID of
+// the session the token belongs to.
+func issueRefreshToken(ctx context.Context, userID, familyID, deviceID string) (string, error) {
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	_, err = db.RefreshTokensCollection.InsertOne(ctx, models.RefreshToken{
+		UserID:    userID,
+		Hash:      hashToken(raw),
+		FamilyID:  familyID,
+		DeviceID:  deviceID,
+		CreatedAt: now,
+		ExpiresAt: now.Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// liveRefreshToken returns the token raw stands for if it can still be
+// rotated, without touching it.
+func liveRefreshToken(ctx context.Context, raw string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := db.RefreshTokensCollection.FindOne(ctx, bson.M{
+		"hash": hashToken(raw), "rotatedAt": nil, "revokedAt": nil, "expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&token)
+	return token, err
+}
+
+// rotateRefreshToken retires raw and issues its successor in the same
+// family. Presenting a token that was already rotated revokes the family.
+func rotateRefreshToken(ctx context.Context, raw string) (models.RefreshToken, string, error) {
+	hash := hashToken(raw)
+	now := time.Now()
+
+	var current models.RefreshToken
+	err := db.RefreshTokensCollection.FindOneAndUpdate(ctx,
+		bson.M{"hash": hash, "rotatedAt": nil, "revokedAt": nil, "expiresAt": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"rotatedAt": now}},
+	).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		var seen models.RefreshToken
+		if db.RefreshTokensCollection.FindOne(ctx, bson.M{"hash": hash}).Decode(&seen) == nil &&
+			seen.RotatedAt != nil && seen.RevokedAt == nil {
+			log.Printf("Refresh token reuse for %s (session %s, device %s); revoking session", seen.UserID, seen.FamilyID, seen.DeviceID)
+			if err := revokeSession(ctx, seen.FamilyID); err != nil {
+				log.Printf("Failed to revoke session %s: %v", seen.FamilyID, err)
+			}
+			return seen, "", errRefreshReused
+		}
+		return current, "", errInvalidRefresh
+	}
+	if err != nil {
+		return current, "", err
+	}
+
+	next, err := issueRefreshToken(ctx, current.UserID, current.FamilyID, current.DeviceID)
+	return current, next, err
+}
+
+// revokeRefreshFamily revokes every live token in a family.
+func revokeRefreshFamily(ctx context.Context, familyID string) error {
+	_, err := db.RefreshTokensCollection.UpdateMany(ctx,
+		bson.M{"familyId": familyID, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	return err
+}
+
+// revokeRefreshToken ends the session raw belongs to, as on logout.
+func revokeRefreshToken(ctx context.Context, raw string) error {
+	var token models.RefreshToken
+	err := db.RefreshTokensCollection.FindOne(ctx, bson.M{"hash": hashToken(raw)},
+		options.FindOne().SetProjection(bson.M{"familyId": 1})).Decode(&token)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return revokeSession(ctx, token.FamilyID)
+}
+
+// POST /api/v1/auth/token/refresh
+//
+// Body: { "refreshToken": "..." }. Returns a new access token and a new
+// refresh token; the one sent is no longer valid.
+func refreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		http.Error(w, "Missing refresh token", http.StatusBadRequest)
+		return
+	}
+
+	// Check the account before rotating, so a suspended user keeps a
+	// refresh token that works again once the suspension ends.
+	var user structs.User
+	if live, err := liveRefreshToken(r.Context(), body.RefreshToken); err == nil {
+		if err := db.UserCollection.FindOne(r.Context(), bson.M{"userid": live.UserID}).Decode(&user); err != nil {
+			revokeSession(r.Context(), live.FamilyID)
+			http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+		if user.SuspendedUntil != nil && user.SuspendedUntil.After(time.Now()) {
+			http.Error(w, "Account suspended until "+user.SuspendedUntil.Format(time.RFC1123), http.StatusForbidden)
+			return
+		}
+	} else if err != mongo.ErrNoDocuments {
+		log.Printf("Refresh token lookup failed: %v", err)
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+
+	current, next, err := rotateRefreshToken(r.Context(), body.RefreshToken)
+	if errors.Is(err, errInvalidRefresh) || errors.Is(err, errRefreshReused) {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		log.Printf("Refresh token rotation failed: %v", err)
+		http.Error(w, "Failed to refresh token", http.StatusInternalServerError)
+		return
+	}
+	if user.UserID != current.UserID {
+		http.Error(w, "Invalid refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	tokenString, err := issueAccessToken(user, current.FamilyID)
+	if err != nil {
+		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		return
+	}
+	touchSession(r.Context(), r, current.FamilyID)
+
+	utils.SendResponse(w, http.StatusOK, map[string]string{
+		"token":        tokenString,
+		"refreshToken": next,
+	}, "Token refreshed successfully", nil)
+}
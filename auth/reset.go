@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/mailer"
+	"naevis/rdx"
+	"naevis/structs"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	resetTokenTTL     = time.Hour
+	resetLimitWindow  = time.Hour
+	resetEmailLimit   = 3  // reset emails per address per window
+	resetIPLimit      = 10 // requests per IP per window, each endpoint
+	minPasswordLength = 8
+)
+
+// The reset token is stored by hash and points at the user; the user key
+// remembers the live token so a newer request voids the older link.
+func resetTokenKey(hash string) string     { return "pwreset:" + hash }
+func resetUserKey(userID string) string    { return "pwreset-user:" + userID }
+func resetLimitKey(scope, v string) string { return "pwreset-limit:" + scope + ":" + v }
+
+// overLimit counts one more hit on key and reports whether it went past
+// limit within window.
+func overLimit(ctx context.Context, key string, limit int64, window time.Duration) bool {
+	n, err := rdx.Conn.Incr(ctx, key).Result()
+	if err != nil {
+		log.Printf("Rate limit check failed for %s: %v", key, err)
+		return false
+	}
+	if n == 1 {
+		rdx.Conn.Expire(ctx, key, window)
+	}
+	return n > limit
+}
+
+// resetLink is what the email points at. PASSWORD_RESET_URL is the page
+// of the web app that takes the token; without it the bare token is sent.
+func resetLink(token string) string {
+	base := os.Getenv("PASSWORD_RESET_URL")
+	if base == "" {
+		return token
+	}
+	sep := "?"
+	if strings.Contains(base, "?") {
+		sep = "&"
+	}
+	return base + sep + "token=" + url.QueryEscape(token)
+}
+
+// POST /api/v1/auth/password/request-reset
+//
+// Body: { "email": "..." }. Emails a single-use reset link. The reply is the
+// same whether or not the address is registered.
+func RequestPasswordReset(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || strings.TrimSpace(input.Email) == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+	email := strings.TrimSpace(input.Email)
+	ctx := r.Context()
+
+	if overLimit(ctx, resetLimitKey("request-ip", clientIP(r)), resetIPLimit, resetLimitWindow) ||
+		overLimit(ctx, resetLimitKey("request-email", strings.ToLower(email)), resetEmailLimit, resetLimitWindow) {
+		http.Error(w, "Too many reset requests, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	reply := map[string]string{"message": "If the address is registered, a reset link is on its way"}
+
+	var user structs.User
+	if err := db.UserCollection.FindOne(ctx, bson.M{"email": email}).Decode(&user); err != nil {
+		json.NewEncoder(w).Encode(reply)
+		return
+	}
+
+	// From here on failures are only logged: an error only registered
+	// addresses can hit would tell them apart from unknown ones.
+	token, err := generateRefreshToken()
+	if err != nil {
+		log.Printf("Failed to generate password reset token for %s: %v", user.UserID, err)
+		json.NewEncoder(w).Encode(reply)
+		return
+	}
+	hash := hashToken(token)
+
+	if old, err := rdx.RdxGet(resetUserKey(user.UserID)); err == nil && old != "" {
+		rdx.RdxDel(resetTokenKey(old))
+	}
+	if err := rdx.SetWithExpiry(resetTokenKey(hash), user.UserID, resetTokenTTL); err != nil {
+		log.Printf("Failed to store password reset token for %s: %v", user.UserID, err)
+		json.NewEncoder(w).Encode(reply)
+		return
+	}
+	rdx.SetWithExpiry(resetUserKey(user.UserID), hash, resetTokenTTL)
+
+	body := "We received a request to reset your password. Use this link within " +
+		"an hour to choose a new one:\n\n" + resetLink(token) +
+		"\n\nIf you didn't ask for this, you can ignore this email; your password stays the same."
+	if err := mailer.Send(user.Email, "Reset your password", body); err != nil {
+		log.Printf("Failed to send password reset email to %s: %v", user.Email, err)
+	}
+
+	json.NewEncoder(w).Encode(reply)
+}
+
+// POST /api/v1/auth/password/confirm-reset
+//
+// Body: { "token": "...", "password": "..." }. Sets the new password, signs
+// the user out everywhere and emails a notice.
+func ConfirmPasswordReset(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.Token == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if len(input.Password) < minPasswordLength {
+		http.Error(w, "Password must be at least 8 characters", http.StatusBadRequest)
+		return
+	}
+	ctx := r.Context()
+
+	if overLimit(ctx, resetLimitKey("confirm-ip", clientIP(r)), resetIPLimit, resetLimitWindow) {
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	hash := hashToken(input.Token)
+	userID, err := rdx.RdxGet(resetTokenKey(hash))
+	if err != nil || userID == "" {
+		http.Error(w, "Invalid or expired reset link", http.StatusBadRequest)
+		return
+	}
+
+	var user structs.User
+	if err := db.UserCollection.FindOne(ctx, bson.M{"userid": userID}).Decode(&user); err != nil {
+		http.Error(w, "Invalid or expired reset link", http.StatusBadRequest)
+		return
+	}
+	if overLimit(ctx, resetLimitKey("confirm-email", strings.ToLower(user.Email)), resetEmailLimit, resetLimitWindow) {
+		http.Error(w, "Too many attempts, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	// Claim the token; only one confirm can win it.
+	if n, err := rdx.Conn.Del(ctx, resetTokenKey(hash)).Result(); err != nil || n == 0 {
+		http.Error(w, "Invalid or expired reset link", http.StatusBadRequest)
+		return
+	}
+	rdx.RdxDel(resetUserKey(userID))
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, "Failed to hash password", http.StatusInternalServerError)
+		return
+	}
+	_, err = db.UserCollection.UpdateOne(ctx,
+		bson.M{"userid": userID},
+		bson.M{"$set": bson.M{"password": string(hashed), "updated_at": time.Now()}},
+	)
+	if err != nil {
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	if err := SignOutEverywhere(ctx, userID); err != nil {
+		log.Printf("Failed to revoke sessions for %s after password reset: %v", userID, err)
+	}
+
+	notice := "The password for your account " + user.Username + " was just changed. " +
+		"All devices have been signed out and your API tokens revoked.\n\nIf this wasn't you, reset your password right away and contact support."
+	if err := mailer.Send(user.Email, "Your password was changed", notice); err != nil {
+		log.Printf("Failed to send password change notice to %s: %v", user.Email, err)
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"message": "Password updated, please log in again"})
+}
+
+// SignOutEverywhere revokes every session, refresh token and personal API
+// token of userID.
+func SignOutEverywhere(ctx context.Context, userID string) error {
+	if _, err := revokeUserSessions(ctx, userID, ""); err != nil {
+		return err
+	}
+	_, err := db.RefreshTokensCollection.UpdateMany(ctx,
+		bson.M{"userid": userID, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = db.APITokensCollection.DeleteMany(ctx, bson.M{"userid": userID})
+	return err
+}
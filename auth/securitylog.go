@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/mailer"
+	"naevis/models"
+	"naevis/notifications"
+	"naevis/structs"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// countryOf is the two-letter country the request came from, as set by a
+// geolocating proxy in the header named by GEOIP_COUNTRY_HEADER (e.g.
+// "CF-IPCountry"). It is empty when no such header is configured.
+func countryOf(r *http.Request) string {
+	header := os.Getenv("GEOIP_COUNTRY_HEADER")
+	if header == "" {
+		return ""
+	}
+	country := strings.ToUpper(strings.TrimSpace(r.Header.Get(header)))
+	if len(country) != 2 || country == "XX" {
+		return ""
+	}
+	return country
+}
+
+// logLoginAttempt writes one login attempt to the security log. user is nil
+// when the username matched no account.
+func logLoginAttempt(ctx context.Context, r *http.Request, username string, user *structs.User, deviceID string, success bool, reason string) {
+	event := models.SecurityEvent{
+		Username:  username,
+		Type:      "login",
+		Success:   success,
+		Reason:    reason,
+		IP:        clientIP(r),
+		UserAgent: r.UserAgent(),
+		DeviceID:  deviceID,
+		Country:   countryOf(r),
+		CreatedAt: time.Now(),
+	}
+	if user != nil {
+		event.UserID = user.UserID
+		event.Username = user.Username
+	}
+	if _, err := db.SecurityEventsCollection.InsertOne(ctx, event); err != nil {
+		log.Printf("Failed to log login attempt for %s: %v", username, err)
+	}
+}
+
+// notifyIfNewLogin tells user when a successful login comes from a device
+// or country none of their earlier logins used. The very first login is
+// not reported. Call it before the login itself is logged. A client that
+// sends no device ID gets a fresh one on every login, so for those
+// (generated) the browser's user agent stands in for the device.
+func notifyIfNewLogin(ctx context.Context, r *http.Request, user structs.User, deviceID, sessionID string, generated bool) {
+	past := bson.M{"userid": user.UserID, "success": true}
+	n, err := db.SecurityEventsCollection.CountDocuments(ctx, past)
+	if err != nil || n == 0 {
+		return
+	}
+
+	var what []string
+	field, device := "deviceId", deviceID
+	if generated {
+		field, device = "userAgent", r.UserAgent()
+	}
+	past[field] = device
+	if n, err := db.SecurityEventsCollection.CountDocuments(ctx, past); err == nil && n == 0 {
+		what = append(what, "a new device")
+	}
+	delete(past, field)
+	country := countryOf(r)
+	if country != "" {
+		past["country"] = country
+		if n, err := db.SecurityEventsCollection.CountDocuments(ctx, past); err == nil && n == 0 {
+			what = append(what, "a new country ("+country+")")
+		}
+	}
+	if len(what) == 0 {
+		return
+	}
+
+	title := "New sign-in from " + strings.Join(what, " and ")
+	body := "Your account " + user.Username + " was signed in to from " + strings.Join(what, " and ") +
+		" at " + time.Now().UTC().Format(time.RFC1123) + ".\n\nIP address: " + clientIP(r) +
+		"\nBrowser: " + r.UserAgent() +
+		"\n\nIf this was you, there's nothing to do. If not, sign that session out and change your password."
+	go notifications.Notify(user.UserID, "security", title, body, "session", sessionID)
+	go func() {
+		if err := mailer.Send(user.Email, title, body); err != nil {
+			log.Printf("Failed to send new sign-in notice to %s: %v", user.Email, err)
+		}
+	}()
+}
+
+// GET /api/v1/auth/security-events?limit=50
+//
+// The caller's recent login attempts, newest first.
+func GetSecurityEvents(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(limit)
+	cursor, err := db.SecurityEventsCollection.Find(r.Context(), bson.M{"userid": utils.GetUserIDFromRequest(r)}, opts)
+	if err != nil {
+		http.Error(w, "Failed to load security events", http.StatusInternalServerError)
+		return
+	}
+	events := []models.SecurityEvent{}
+	if err := cursor.All(r.Context(), &events); err != nil {
+		http.Error(w, "Failed to load security events", http.StatusInternalServerError)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"events": events})
+}
@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"naevis/db"
+	"naevis/globals"
+	"naevis/middleware"
+	"naevis/models"
+	"naevis/rdx"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// clientIP is the caller's address without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// startSession records a login on deviceID and issues its first refresh
+// token. A device holds one session at a time, so any earlier session on
+// the same device is revoked.
+func startSession(ctx context.Context, r *http.Request, userID, deviceID string) (string, string, error) {
+	cursor, err := db.SessionsCollection.Find(ctx, bson.M{"userid": userID, "deviceId": deviceID, "revokedAt": nil})
+	if err != nil {
+		return "", "", err
+	}
+	var previous []models.Session
+	if err := cursor.All(ctx, &previous); err != nil {
+		return "", "", err
+	}
+	for _, s := range previous {
+		if err := revokeSession(ctx, s.ID); err != nil {
+			return "", "", err
+		}
+	}
+
+	sessionID, err := generateRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+	now := time.Now()
+	_, err = db.SessionsCollection.InsertOne(ctx, models.Session{
+		ID:         sessionID,
+		UserID:     userID,
+		DeviceID:   deviceID,
+		IP:         clientIP(r),
+		UserAgent:  r.UserAgent(),
+		CreatedAt:  now,
+		LastSeenAt: now,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	refreshToken, err := issueRefreshToken(ctx, userID, sessionID, deviceID)
+	return sessionID, refreshToken, err
+}
+
+// touchSession notes that the session was just used from r.
+func touchSession(ctx context.Context, r *http.Request, sessionID string) {
+	_, err := db.SessionsCollection.UpdateByID(ctx, sessionID, bson.M{"$set": bson.M{
+		"lastSeenAt": time.Now(),
+		"ip":         clientIP(r),
+		"userAgent":  r.UserAgent(),
+	}})
+	if err != nil {
+		log.Printf("Failed to update session %s: %v", sessionID, err)
+	}
+}
+
+// revokeSession ends a session: its refresh tokens stop working and its
+// access tokens are refused by middleware.Authenticate until they expire.
+func revokeSession(ctx context.Context, sessionID string) error {
+	_, err := db.SessionsCollection.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if err := revokeRefreshFamily(ctx, sessionID); err != nil {
+		return err
+	}
+	return rdx.SetWithExpiry(middleware.RevokedSessionKey(sessionID), "1", accessTokenTTL)
+}
+
+// revokeUserSessions ends every live session of userID except keep.
+func revokeUserSessions(ctx context.Context, userID, keep string) (int, error) {
+	cursor, err := db.SessionsCollection.Find(ctx, bson.M{"userid": userID, "revokedAt": nil, "_id": bson.M{"$ne": keep}})
+	if err != nil {
+		return 0, err
+	}
+	var sessions []models.Session
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return 0, err
+	}
+	for _, s := range sessions {
+		if err := revokeSession(ctx, s.ID); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
+
+func currentSessionID(r *http.Request) string {
+	sid, _ := r.Context().Value(globals.SessionIDKey).(string)
+	return sid
+}
+
+// GET /api/v1/auth/sessions
+//
+// Lists the caller's active sessions, most recently used first.
+func GetSessions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	filter := bson.M{
+		"userid":     userID,
+		"revokedAt":  nil,
+		"lastSeenAt": bson.M{"$gt": time.Now().Add(-refreshTokenTTL)},
+	}
+	cursor, err := db.SessionsCollection.Find(r.Context(), filter, options.Find().SetSort(bson.M{"lastSeenAt": -1}))
+	if err != nil {
+		http.Error(w, "Failed to load sessions", http.StatusInternalServerError)
+		return
+	}
+	sessions := []models.Session{}
+	if err := cursor.All(r.Context(), &sessions); err != nil {
+		http.Error(w, "Failed to load sessions", http.StatusInternalServerError)
+		return
+	}
+
+	current := currentSessionID(r)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"sessions": sessions})
+}
+
+// DELETE /api/v1/auth/sessions/:id
+//
+// Revokes one of the caller's sessions, including the current one.
+func RevokeSession(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	sessionID := ps.ByName("id")
+
+	n, err := db.SessionsCollection.CountDocuments(r.Context(), bson.M{"_id": sessionID, "userid": userID})
+	if err != nil {
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	if n == 0 {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	if err := revokeSession(r.Context(), sessionID); err != nil {
+		log.Printf("Failed to revoke session %s: %v", sessionID, err)
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"revoked": 1})
+}
+
+// DELETE /api/v1/auth/sessions
+//
+// Revokes every session of the caller except the one making the request.
+func RevokeOtherSessions(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	n, err := revokeUserSessions(r.Context(), userID, currentSessionID(r))
+	if err != nil {
+		log.Printf("Failed to revoke sessions for %s: %v", userID, err)
+		http.Error(w, "Failed to revoke sessions", http.StatusInternalServerError)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"revoked": n})
+}
@@ -0,0 +1,167 @@
+// Package blocks keeps each user's block list. A block stops chats, direct
+// messages and follows between the two users in either direction, and
+// hides the blocked user's comments and reviews from the blocker.
+package blocks
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/utils"
+)
+
+// IsBlocked reports whether either user has blocked the other.
+func IsBlocked(ctx context.Context, a, b string) bool {
+	if a == "" || b == "" || a == b {
+		return false
+	}
+	n, err := db.BlocksCollection.CountDocuments(ctx, bson.M{"$or": []bson.M{
+		{"blocker": a, "blocked": b},
+		{"blocker": b, "blocked": a},
+	}})
+	if err != nil {
+		log.Printf("Block lookup failed for %s/%s: %v", a, b, err)
+		return false
+	}
+	return n > 0
+}
+
+// AnyBlocked reports whether userID and any of others have blocked one
+// another. userID itself may appear in others.
+func AnyBlocked(ctx context.Context, userID string, others []string) bool {
+	for _, other := range others {
+		if IsBlocked(ctx, userID, other) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockedIDs returns the users userID has blocked. Readers use it to leave
+// those users' content out of what userID sees.
+func BlockedIDs(ctx context.Context, userID string) []string {
+	ids := []string{}
+	if userID == "" {
+		return ids
+	}
+	cursor, err := db.BlocksCollection.Find(ctx, bson.M{"blocker": userID})
+	if err != nil {
+		log.Printf("Block list lookup failed for %s: %v", userID, err)
+		return ids
+	}
+	defer cursor.Close(ctx)
+
+	var list []models.Block
+	if err := cursor.All(ctx, &list); err != nil {
+		log.Printf("Block list decode failed for %s: %v", userID, err)
+		return ids
+	}
+	for _, b := range list {
+		ids = append(ids, b.BlockedID)
+	}
+	return ids
+}
+
+// unfollowBoth removes follow edges between a and b in both directions.
+func unfollowBoth(ctx context.Context, a, b string) error {
+	pairs := [][2]string{{a, b}, {b, a}}
+	for _, p := range pairs {
+		_, err := db.FollowingsCollection.UpdateOne(ctx,
+			bson.M{"userid": p[0]},
+			bson.M{"$pull": bson.M{"follows": p[1], "followers": p[1]}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GET /api/v1/blocks
+//
+// Lists the users the caller has blocked.
+func GetBlocks(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	cursor, err := db.BlocksCollection.Find(r.Context(), bson.M{"blocker": userID},
+		options.Find().SetSort(bson.M{"createdAt": -1}))
+	if err != nil {
+		http.Error(w, "Failed to load blocks", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	list := []models.Block{}
+	if err := cursor.All(r.Context(), &list); err != nil {
+		http.Error(w, "Failed to load blocks", http.StatusInternalServerError)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"blocks": list})
+}
+
+// PUT /api/v1/blocks/:userid
+//
+// Blocks a user and drops any follow between the two. Blocking twice is a
+// no-op.
+func BlockUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	target := ps.ByName("userid")
+	if target == "" || target == userID {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+	if n, err := db.UserCollection.CountDocuments(r.Context(), bson.M{"userid": target}); err != nil || n == 0 {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	_, err := db.BlocksCollection.UpdateOne(r.Context(),
+		bson.M{"blocker": userID, "blocked": target},
+		bson.M{"$setOnInsert": bson.M{"createdAt": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		http.Error(w, "Failed to block user", http.StatusInternalServerError)
+		return
+	}
+
+	if err := unfollowBoth(r.Context(), userID, target); err != nil {
+		log.Printf("Failed to remove follows between %s and %s: %v", userID, target, err)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"blocked": true})
+}
+
+// DELETE /api/v1/blocks/:userid
+//
+// Unblocks a user. Follows removed by the block are not restored.
+func UnblockUser(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	_, err := db.BlocksCollection.DeleteOne(r.Context(), bson.M{"blocker": userID, "blocked": ps.ByName("userid")})
+	if err != nil {
+		http.Error(w, "Failed to unblock user", http.StatusInternalServerError)
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"blocked": false})
+}
@@ -219,12 +219,9 @@ func PlaceOrder(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	order.OrderID = "ORD" + strconv.FormatInt(time.Now().UnixNano()%1e6, 10)
 	order.CreatedAt = time.Now()
 
-	if order.Status == "" {
-		order.Status = "pending"
-	}
-	if order.ApprovedBy == nil {
-		order.ApprovedBy = []string{}
-	}
+	// Status and approvals are the server's to set, never the client's
+	order.Status = "pending"
+	order.ApprovedBy = []string{}
 
 	if _, err := db.OrderCollection.InsertOne(ctx, order); err != nil {
 		log.Println("PlaceOrder InsertOne error:", err)
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"naevis/blocks"
+	"naevis/contentfilter"
 	"naevis/db"
 	"naevis/middleware"
 	"naevis/models"
@@ -61,18 +63,42 @@ func ChatWebSocket(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 			break
 		}
 
+		if middleware.CheckAccess(claims) != nil {
+			break
+		}
+
 		msg.UserID = userID
 		msg.CreatedAt = time.Now()
 
+		participants := getChatUserIDs(msg.ChatID)
+		if blocks.AnyBlocked(context.TODO(), userID, participants) {
+			_ = conn.WriteJSON(map[string]string{"error": "You can't message this chat"})
+			continue
+		}
+
+		verdict := contentfilter.Check(context.TODO(), contentfilter.Input{UserID: userID, EntityType: "message", Text: msg.Text})
+		if verdict.Verdict == contentfilter.Reject {
+			_ = conn.WriteJSON(map[string]string{"error": "Message rejected: " + verdict.Reason})
+			continue
+		}
+		msg.Hidden = verdict.Verdict == contentfilter.Hold
+
 		// Insert to DB
-		_, err = db.MessagesCollection.InsertOne(context.TODO(), msg)
+		res, err := db.MessagesCollection.InsertOne(context.TODO(), msg)
 		if err != nil {
 			log.Println("Mongo insert error:", err)
 			continue
 		}
+		if msg.Hidden {
+			msgID := res.InsertedID.(primitive.ObjectID).Hex()
+			if err := contentfilter.HoldForReview(context.TODO(), "message", msgID, userID, verdict, true); err != nil {
+				log.Printf("Failed to queue held message %s: %v", msgID, err)
+			}
+			continue
+		}
 
 		// Broadcast to all chat participants
-		for _, uid := range getChatUserIDs(msg.ChatID) {
+		for _, uid := range participants {
 			clientsMu.Lock()
 			if c, ok := clients[uid]; ok && c != conn {
 				_ = c.WriteJSON(msg)
@@ -136,6 +162,7 @@ func GetChat(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Fetch messages (sorted by CreatedAt ascending)
 	cursor, err := db.MessagesCollection.Find(ctx, bson.M{
 		"chatID": chatIDHex,
+		"hidden": bson.M{"$ne": true},
 	}, options.Find().SetSort(bson.M{"createdAt": 1}))
 	if err != nil {
 		http.Error(w, "DB error", http.StatusInternalServerError)
@@ -228,7 +255,7 @@ func CreateMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 			break
 		}
 	}
-	if !isParticipant {
+	if !isParticipant || blocks.AnyBlocked(ctx, claims.UserID, chat.Users) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
@@ -266,6 +293,12 @@ func CreateMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
+	verdict := contentfilter.Check(ctx, contentfilter.Input{UserID: claims.UserID, EntityType: "message", Text: text})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "Message rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+
 	// 9) Build the Message object (including optional ReplyTo)
 	now := time.Now()
 	msg := models.Message{
@@ -275,6 +308,7 @@ func CreateMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		FileURL:   fileURL,
 		FileType:  fileType,
 		CreatedAt: now,
+		Hidden:    verdict.Verdict == contentfilter.Hold,
 	}
 	if replyRef != nil {
 		msg.ReplyTo = &models.ReplyRef{
@@ -290,6 +324,15 @@ func CreateMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		http.Error(w, "Insert failed", http.StatusInternalServerError)
 		return
 	}
+	msg.ID = res.InsertedID.(primitive.ObjectID)
+	if msg.Hidden {
+		if err := contentfilter.HoldForReview(ctx, "message", msg.ID.Hex(), claims.UserID, verdict, true); err != nil {
+			log.Printf("Failed to queue held message %s: %v", msg.ID.Hex(), err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(msg)
+		return
+	}
 
 	// 11) Update chat’s lastMessage and updatedAt
 	db.ChatsCollection.UpdateOne(
@@ -308,7 +351,6 @@ func CreateMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	)
 
 	// 12) Return the created message as JSON
-	msg.ID = res.InsertedID.(primitive.ObjectID)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(msg)
 }
@@ -352,11 +394,26 @@ func UpdateMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	_, err = coll.UpdateOne(ctx, bson.M{"_id": msgID}, bson.M{"$set": bson.M{"text": input.Text}})
+	verdict := contentfilter.Check(ctx, contentfilter.Input{UserID: claims.UserID, EntityType: "message", Text: input.Text})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "Message rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+	set := bson.M{"text": input.Text}
+	if verdict.Verdict == contentfilter.Hold {
+		set["hidden"] = true
+	}
+
+	_, err = coll.UpdateOne(ctx, bson.M{"_id": msgID}, bson.M{"$set": set})
 	if err != nil {
 		http.Error(w, "Update failed", http.StatusInternalServerError)
 		return
 	}
+	if verdict.Verdict == contentfilter.Hold {
+		if err := contentfilter.HoldForReview(ctx, "message", msgID.Hex(), claims.UserID, verdict, true); err != nil {
+			log.Printf("Failed to queue held message %s: %v", msgID.Hex(), err)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -421,6 +478,11 @@ func InitChat(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 
+	if blocks.IsBlocked(r.Context(), body.UserA, body.UserB) {
+		http.Error(w, "Cannot start a chat with this user", http.StatusForbidden)
+		return
+	}
+
 	users := []string{body.UserA, body.UserB}
 	sort.Strings(users)
 
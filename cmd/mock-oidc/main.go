@@ -0,0 +1,37 @@
+// Command mock-oidc runs the oidctest issuer for local development, so
+// social login can be tried without a real provider:
+//
+//	go run ./cmd/mock-oidc -addr :9998 -client farmium
+//
+// and list it in the OIDC_PROVIDERS file:
+//
+//	[{"name": "mock", "issuer": "http://localhost:9998", "client_id": "farmium",
+//	  "redirect_url": "http://localhost:3000/oidc/callback"}]
+//
+// Every authorization signs in straight away, as the default user or as
+// the address given in login_hint.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"naevis/oidc/oidctest"
+)
+
+func main() {
+	addr := flag.String("addr", ":9998", "listen address")
+	issuer := flag.String("issuer", "http://localhost:9998", "issuer URL as clients reach it")
+	client := flag.String("client", "farmium", "client_id to accept")
+	flag.Parse()
+
+	iss, err := oidctest.NewIssuer(*client)
+	if err != nil {
+		log.Fatalf("❌ Creating issuer failed: %v", err)
+	}
+	iss.URL = *issuer
+
+	log.Printf("✅ Mock OIDC issuer %s listening on %s", iss.URL, *addr)
+	log.Fatal(http.ListenAndServe(*addr, iss))
+}
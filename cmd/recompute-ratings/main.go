@@ -0,0 +1,26 @@
+// Command recompute-ratings rebuilds review aggregates (average, count and
+// star histogram) from the reviews collection and copies them back onto
+// farms, crops and recipes. Run it after restoring data or to repair drift:
+//
+//	go run ./cmd/recompute-ratings
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"naevis/reviews"
+)
+
+func main() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	start := time.Now()
+	n, err := reviews.RecomputeRatings(ctx)
+	if err != nil {
+		log.Fatalf("❌ Recomputing ratings failed after %d entities: %v", n, err)
+	}
+	log.Printf("✅ Recomputed ratings for %d entities in %v", n, time.Since(start))
+}
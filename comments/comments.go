@@ -10,43 +10,91 @@ import (
 	"github.com/julienschmidt/httprouter"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"naevis/contentfilter"
 	"naevis/db"
 	"naevis/middleware"
 	"naevis/models"
+	"naevis/owners"
 	"naevis/utils"
 )
 
+// canModifyComment allows the comment's author, the owner of the commented
+// entity, and moderators to edit or delete a comment.
+func canModifyComment(r *http.Request, comment models.Comment) bool {
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		return false
+	}
+	if comment.CreatedBy == userID || middleware.IsModerator(r.Context(), userID) {
+		return true
+	}
+	owner, err := owners.EntityOwner(r.Context(), comment.EntityType, comment.EntityID)
+	return err == nil && owner == userID
+}
+
 func CreateComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	log.Println("ok")
 	entityType := ps.ByName("entitytype")
 	entityID := ps.ByName("entityid")
 
 	var body struct {
-		Content string `json:"content"`
+		Content  string `json:"content"`
+		ParentID string `json:"parentId"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
 
-	tokenString := r.Header.Get("Authorization")
-	claims, err := middleware.ValidateJWT(tokenString)
-	if err != nil {
-		log.Printf("JWT validation error: %v", err) // Log the error for debugging
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{UserID: userID, EntityType: "comment", Text: body.Content})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "Comment rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+
+	var err error
 	comment := models.Comment{
 		EntityType: entityType,
 		EntityID:   entityID,
-		CreatedBy:  claims.UserID,
+		CreatedBy:  userID,
 		Content:    body.Content,
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
+		Hidden:     verdict.Verdict == contentfilter.Hold,
 	}
 
+	// Replies inherit the thread's entity and sit one level below their parent.
+	var parentObjID primitive.ObjectID
+	if body.ParentID != "" {
+		parentObjID, err = primitive.ObjectIDFromHex(body.ParentID)
+		if err != nil {
+			http.Error(w, "Invalid parent ID", http.StatusBadRequest)
+			return
+		}
+		var parent models.Comment
+		err = db.CommentsCollection.FindOne(context.TODO(), bson.M{"_id": parentObjID, "entity_type": entityType, "entity_id": entityID}).Decode(&parent)
+		if err != nil {
+			http.Error(w, "Parent comment not found", http.StatusNotFound)
+			return
+		}
+		if parent.Depth+1 > maxCommentDepth {
+			http.Error(w, "Reply nesting limit reached", http.StatusBadRequest)
+			return
+		}
+		comment.ParentID = body.ParentID
+		comment.Depth = parent.Depth + 1
+	}
+
+	comment.Mentions = resolveMentions(r.Context(), comment.Content, userID)
+
 	res, err := db.CommentsCollection.InsertOne(context.TODO(), comment)
 	if err != nil {
 		http.Error(w, "DB insert failed", http.StatusInternalServerError)
@@ -54,6 +102,18 @@ func CreateComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	}
 	comment.ID = res.InsertedID.(primitive.ObjectID).Hex()
 
+	if comment.ParentID != "" {
+		_, _ = db.CommentsCollection.UpdateByID(context.TODO(), parentObjID, bson.M{"$inc": bson.M{"reply_count": 1}})
+	}
+	InvalidateCommentCount(entityType, entityID)
+	if comment.Hidden {
+		if err := contentfilter.HoldForReview(r.Context(), "comment", comment.ID, userID, verdict, true); err != nil {
+			log.Printf("Failed to queue held comment %s: %v", comment.ID, err)
+		}
+	} else {
+		go notifyMentions(comment, nil)
+	}
+
 	utils.RespondWithJSON(w, http.StatusOK, comment)
 }
 
@@ -61,37 +121,61 @@ func GetComments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	entityType := ps.ByName("entitytype")
 	entityID := ps.ByName("entityid")
 
-	filter := bson.M{"entity_type": entityType, "entity_id": entityID}
-	cursor, err := db.CommentsCollection.Find(context.TODO(), filter)
+	// Only top-level comments are listed here; replies are paged per thread via GetReplies.
+	page, limit := pageParams(r)
+	filter := bson.M{"entity_type": entityType, "entity_id": entityID, "parent_id": bson.M{"$exists": false}, "hidden": bson.M{"$ne": true}}
+	hideBlocked(r, filter)
+
+	sortDir := -1
+	if r.URL.Query().Get("sort") == "oldest" {
+		sortDir = 1
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: sortDir}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := db.CommentsCollection.Find(context.TODO(), filter, opts)
 	if err != nil {
 		http.Error(w, "DB find failed", http.StatusInternalServerError)
 		return
 	}
 	defer cursor.Close(context.TODO())
 
-	var comments []models.Comment
+	comments := []models.Comment{}
 	if err := cursor.All(context.TODO(), &comments); err != nil {
 		http.Error(w, "Cursor decode failed", http.StatusInternalServerError)
 		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, comments)
+	threads, err := db.CommentsCollection.CountDocuments(context.TODO(), filter)
+	if err != nil {
+		http.Error(w, "DB count failed", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"comments": comments,
+		"threads":  threads,
+		"total":    CommentCount(r.Context(), entityType, entityID),
+		"page":     page,
+		"limit":    limit,
+	})
 }
 
+// GET /api/v1/comments/:entitytype/:entityid/:commentid
 func GetComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	commentID := ps.ByName("entitytype")
-
-	filter := bson.M{"_id": commentID}
-	cursor, err := db.CommentsCollection.Find(context.TODO(), filter)
+	objID, err := primitive.ObjectIDFromHex(ps.ByName("commentid"))
 	if err != nil {
-		http.Error(w, "DB find failed", http.StatusInternalServerError)
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
 		return
 	}
-	defer cursor.Close(context.TODO())
 
 	var comment models.Comment
-	if err := cursor.All(context.TODO(), &comment); err != nil {
-		http.Error(w, "Cursor decode failed", http.StatusInternalServerError)
+	filter := bson.M{"_id": objID, "entity_type": ps.ByName("entitytype"), "entity_id": ps.ByName("entityid"), "hidden": bson.M{"$ne": true}}
+	hideBlocked(r, filter)
+	if err := db.CommentsCollection.FindOne(context.TODO(), filter).Decode(&comment); err != nil {
+		http.Error(w, "Comment not found", http.StatusNotFound)
 		return
 	}
 
@@ -115,14 +199,6 @@ func UpdateComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	tokenString := r.Header.Get("Authorization")
-	claims, err := middleware.ValidateJWT(tokenString)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Check if comment exists and belongs to user
 	var existing models.Comment
 	err = db.CommentsCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&existing)
 	if err != nil {
@@ -130,17 +206,27 @@ func UpdateComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	if existing.CreatedBy != claims.UserID {
+	if !canModifyComment(r, existing) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
-	update := bson.M{
-		"$set": bson.M{
-			"content":    body.Content,
-			"updated_at": time.Now(),
-		},
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{UserID: existing.CreatedBy, EntityType: "comment", Text: body.Content})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "Comment rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+
+	mentions := resolveMentions(r.Context(), body.Content, existing.CreatedBy)
+	fields := bson.M{
+		"content":    body.Content,
+		"mentions":   mentions,
+		"updated_at": time.Now(),
+	}
+	if verdict.Verdict == contentfilter.Hold {
+		fields["hidden"] = true
 	}
+	update := bson.M{"$set": fields}
 
 	_, err = db.CommentsCollection.UpdateByID(context.TODO(), objID, update)
 	if err != nil {
@@ -153,6 +239,14 @@ func UpdateComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		http.Error(w, "Fetch failed", http.StatusInternalServerError)
 		return
 	}
+	if verdict.Verdict == contentfilter.Hold {
+		if err := contentfilter.HoldForReview(r.Context(), "comment", commentID, existing.CreatedBy, verdict, true); err != nil {
+			log.Printf("Failed to queue held comment %s: %v", commentID, err)
+		}
+		InvalidateCommentCount(existing.EntityType, existing.EntityID)
+	} else if !updated.Hidden {
+		go notifyMentions(updated, existing.Mentions)
+	}
 
 	utils.RespondWithJSON(w, http.StatusOK, updated)
 }
@@ -205,13 +299,6 @@ func DeleteComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	tokenString := r.Header.Get("Authorization")
-	claims, err := middleware.ValidateJWT(tokenString)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
 	var existing models.Comment
 	err = db.CommentsCollection.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&existing)
 	if err != nil {
@@ -219,16 +306,20 @@ func DeleteComment(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	if existing.CreatedBy != claims.UserID {
+	if !canModifyComment(r, existing) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
-	_, err = db.CommentsCollection.DeleteOne(context.TODO(), bson.M{"_id": objID})
-	if err != nil {
+	// Deleting a comment takes its replies with it.
+	if _, err := deleteThread(context.TODO(), objID); err != nil {
 		http.Error(w, "Delete failed", http.StatusInternalServerError)
 		return
 	}
+	if parentObjID, err := primitive.ObjectIDFromHex(existing.ParentID); err == nil {
+		_, _ = db.CommentsCollection.UpdateByID(context.TODO(), parentObjID, bson.M{"$inc": bson.M{"reply_count": -1}})
+	}
+	InvalidateCommentCount(existing.EntityType, existing.EntityID)
 
 	w.WriteHeader(http.StatusNoContent)
 }
@@ -0,0 +1,233 @@
+package comments
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"naevis/blocks"
+	"naevis/db"
+	"naevis/models"
+	"naevis/notifications"
+	"naevis/rdx"
+	"naevis/utils"
+)
+
+// maxCommentDepth is the deepest a reply may nest; top-level comments are depth 0.
+const maxCommentDepth = 3
+
+const commentCountTTL = time.Hour
+
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@])@([A-Za-z0-9_.]{3,30})`)
+
+// resolveMentions maps the @usernames in content to user IDs, skipping
+// unknown names and the author.
+func resolveMentions(ctx context.Context, content, authorID string) []string {
+	seen := map[string]bool{}
+	var usernames []string
+	for _, m := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		name := strings.TrimRight(m[1], ".")
+		if !seen[name] {
+			seen[name] = true
+			usernames = append(usernames, name)
+		}
+	}
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	cursor, err := db.UserCollection.Find(ctx, bson.M{"username": bson.M{"$in": usernames}},
+		options.Find().SetProjection(bson.M{"userid": 1}))
+	if err != nil {
+		log.Printf("Mention lookup failed: %v", err)
+		return nil
+	}
+	defer cursor.Close(ctx)
+
+	var userIDs []string
+	for cursor.Next(ctx) {
+		var u struct {
+			UserID string `bson:"userid"`
+		}
+		if err := cursor.Decode(&u); err == nil && u.UserID != "" && u.UserID != authorID {
+			userIDs = append(userIDs, u.UserID)
+		}
+	}
+	return userIDs
+}
+
+// notifyMentions tells newly mentioned users about a comment. previous holds
+// user IDs that were already notified for an earlier version of it.
+func notifyMentions(comment models.Comment, previous []string) {
+	already := map[string]bool{}
+	for _, id := range previous {
+		already[id] = true
+	}
+	for _, userID := range comment.Mentions {
+		if already[userID] {
+			continue
+		}
+		notifications.Notify(userID, "mention", "You were mentioned in a comment", comment.Content, comment.EntityType, comment.EntityID)
+	}
+}
+
+func commentCountKey(entityType, entityID string) string {
+	return "comments:count:" + entityType + ":" + entityID
+}
+
+// CommentCount returns the total number of comments (including replies) on
+// an entity, served from Redis when cached.
+func CommentCount(ctx context.Context, entityType, entityID string) int64 {
+	key := commentCountKey(entityType, entityID)
+	if v, err := rdx.RdxGet(key); err == nil {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	}
+
+	n, err := db.CommentsCollection.CountDocuments(ctx, bson.M{"entity_type": entityType, "entity_id": entityID, "hidden": bson.M{"$ne": true}})
+	if err != nil {
+		log.Printf("Comment count failed for %s %s: %v", entityType, entityID, err)
+		return 0
+	}
+	// Zero isn't cached so made-up IDs can't fill Redis
+	if n > 0 {
+		_ = rdx.SetWithExpiry(key, strconv.FormatInt(n, 10), commentCountTTL)
+	}
+	return n
+}
+
+// InvalidateCommentCount drops the cached total so the next read recounts.
+// Moderation calls it after hiding or restoring a comment.
+func InvalidateCommentCount(entityType, entityID string) {
+	_, _ = rdx.RdxDel(commentCountKey(entityType, entityID))
+}
+
+// maxCountIDs caps how many entities one comment count request covers.
+const maxCountIDs = 30
+
+// GET /api/v1/commentcounts/:entitytype?ids=a,b,c
+func GetCommentCounts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	entityType := ps.ByName("entitytype")
+
+	counts := map[string]int64{}
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" && len(counts) < maxCountIDs {
+			counts[id] = CommentCount(r.Context(), entityType, id)
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, counts)
+}
+
+// GET /api/v1/comments/:entitytype/:entityid/:commentid/replies?page=1&limit=10
+//
+// Replies are returned oldest first so a thread reads top to bottom.
+func GetReplies(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	parentID := ps.ByName("commentid")
+	if _, err := primitive.ObjectIDFromHex(parentID); err != nil {
+		http.Error(w, "Invalid ID", http.StatusBadRequest)
+		return
+	}
+
+	page, limit := pageParams(r)
+	filter := bson.M{"parent_id": parentID, "hidden": bson.M{"$ne": true}}
+	hideBlocked(r, filter)
+
+	total, err := db.CommentsCollection.CountDocuments(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "DB count failed", http.StatusInternalServerError)
+		return
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := db.CommentsCollection.Find(r.Context(), filter, opts)
+	if err != nil {
+		http.Error(w, "DB find failed", http.StatusInternalServerError)
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	replies := []models.Comment{}
+	if err := cursor.All(r.Context(), &replies); err != nil {
+		http.Error(w, "Cursor decode failed", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{
+		"replies": replies,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// hideBlocked leaves out comments by users the caller has blocked.
+func hideBlocked(r *http.Request, filter bson.M) {
+	if blocked := blocks.BlockedIDs(r.Context(), utils.GetUserIDFromRequest(r)); len(blocked) > 0 {
+		filter["created_by"] = bson.M{"$nin": blocked}
+	}
+}
+
+func pageParams(r *http.Request) (int, int) {
+	page := utils.ParseInt(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit := utils.ParseInt(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// deleteThread removes a comment and every reply beneath it, returning how
+// many comments were deleted.
+func deleteThread(ctx context.Context, root primitive.ObjectID) (int64, error) {
+	ids := []primitive.ObjectID{root}
+	parents := []string{root.Hex()}
+	for depth := 0; depth < maxCommentDepth && len(parents) > 0; depth++ {
+		cursor, err := db.CommentsCollection.Find(ctx, bson.M{"parent_id": bson.M{"$in": parents}},
+			options.Find().SetProjection(bson.M{"_id": 1}))
+		if err != nil {
+			return 0, err
+		}
+		var children []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.All(ctx, &children); err != nil {
+			return 0, err
+		}
+		parents = parents[:0]
+		for _, c := range children {
+			ids = append(ids, c.ID)
+			parents = append(parents, c.ID.Hex())
+		}
+	}
+
+	res, err := db.CommentsCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+
+	hexIDs := make([]string, len(ids))
+	for i, id := range ids {
+		hexIDs[i] = id.Hex()
+	}
+	if _, err := db.ReactionsCollection.DeleteMany(ctx, bson.M{"entity_type": "comment", "entity_id": bson.M{"$in": hexIDs}}); err != nil {
+		log.Printf("Failed to delete reactions for comments: %v", err)
+	}
+	return res.DeletedCount, nil
+}
@@ -0,0 +1,178 @@
+package contentfilter
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"naevis/rdx"
+)
+
+// leet maps look-alike characters back to the letters they stand for.
+var leet = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "7", "t", "8", "b", "9", "g",
+	"@", "a", "$", "s", "!", "i", "|", "l", "+", "t",
+)
+
+// normalize lowercases text, undoes leetspeak and drops punctuation inside
+// words, so "B.4.D" and "b@d" both become "bad". Runs of single letters are
+// joined to catch "b a d".
+func normalize(text string) []string {
+	var words []string
+	var letters strings.Builder
+	flush := func() {
+		if letters.Len() > 0 {
+			words = append(words, letters.String())
+			letters.Reset()
+		}
+	}
+
+	for _, field := range strings.Fields(leet.Replace(strings.ToLower(text))) {
+		var b strings.Builder
+		for _, r := range field {
+			if unicode.IsLetter(r) {
+				b.WriteRune(r)
+			}
+		}
+		word := b.String()
+		if len([]rune(word)) == 1 {
+			letters.WriteString(word)
+			continue
+		}
+		flush()
+		if word != "" {
+			words = append(words, word)
+		}
+	}
+	flush()
+	return words
+}
+
+// squeeze collapses repeated letters: "baaad" becomes "bad".
+func squeeze(word string) string {
+	var b strings.Builder
+	var last rune
+	for i, r := range word {
+		if i == 0 || r != last {
+			b.WriteRune(r)
+		}
+		last = r
+	}
+	return b.String()
+}
+
+// bannedWords matches words from BANNED_WORDS (comma separated) and
+// BANNED_WORDS_FILE (one per line). Matches are rejected unless
+// BANNED_WORDS_VERDICT is "hold".
+type bannedWords struct {
+	words   map[string]bool
+	verdict Verdict
+}
+
+func newBannedWords() *bannedWords {
+	b := &bannedWords{words: map[string]bool{}, verdict: Reject}
+	if os.Getenv("BANNED_WORDS_VERDICT") == "hold" {
+		b.verdict = Hold
+	}
+
+	add := func(raw string) {
+		for _, w := range normalize(raw) {
+			b.words[w] = true
+		}
+	}
+	for _, w := range strings.Split(os.Getenv("BANNED_WORDS"), ",") {
+		add(w)
+	}
+	if path := os.Getenv("BANNED_WORDS_FILE"); path != "" {
+		if f, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(f)
+			for scanner.Scan() {
+				add(scanner.Text())
+			}
+			f.Close()
+		}
+	}
+	return b
+}
+
+func (b *bannedWords) Name() string { return "banned-words" }
+
+func (b *bannedWords) Check(_ context.Context, in Input) Result {
+	if len(b.words) == 0 {
+		return Result{}
+	}
+	for _, word := range normalize(in.Text) {
+		if b.words[word] {
+			return Result{Verdict: b.verdict, Reason: "contains a banned word"}
+		}
+		// Stretched words ("baaad") only count when longer than the
+		// original, so short innocent words don't collide.
+		for banned := range b.words {
+			if len(word) > len(banned) && squeeze(word) == squeeze(banned) {
+				return Result{Verdict: b.verdict, Reason: "contains a banned word"}
+			}
+		}
+	}
+	return Result{}
+}
+
+var linkPattern = regexp.MustCompile(`(?i)\b(?:https?://|www\.)\S+`)
+
+const (
+	maxLinks         = 3
+	maxLinkShare     = 0.3 // links as a share of all words
+	minLinksForShare = 2
+)
+
+// linkDensity holds text that is mostly links.
+type linkDensity struct{}
+
+func (linkDensity) Name() string { return "link-density" }
+
+func (linkDensity) Check(_ context.Context, in Input) Result {
+	links := len(linkPattern.FindAllString(in.Text, -1))
+	words := len(strings.Fields(in.Text))
+	if links > maxLinks || (links >= minLinksForShare && float64(links) > maxLinkShare*float64(words)) {
+		return Result{Verdict: Hold, Reason: "too many links"}
+	}
+	return Result{}
+}
+
+const (
+	repeatWindow  = 10 * time.Minute
+	repeatLimit   = 3
+	repeatMinSize = 12 // short replies like "thanks!" repeat innocently
+)
+
+// repeatPosting holds the same text posted by one user several times
+// within repeatWindow, counted in Redis.
+type repeatPosting struct{}
+
+func (repeatPosting) Name() string { return "repeat-posting" }
+
+func (repeatPosting) Check(ctx context.Context, in Input) Result {
+	text := strings.Join(normalize(in.Text), " ")
+	if in.UserID == "" || len(text) < repeatMinSize {
+		return Result{}
+	}
+
+	sum := sha1.Sum([]byte(text))
+	key := "contentfilter:repeat:" + in.UserID + ":" + hex.EncodeToString(sum[:])
+	n, err := rdx.Conn.Incr(ctx, key).Result()
+	if err != nil {
+		return Result{}
+	}
+	if n == 1 {
+		rdx.Conn.Expire(ctx, key, repeatWindow)
+	}
+	if n >= repeatLimit {
+		return Result{Verdict: Hold, Reason: "same text posted repeatedly"}
+	}
+	return Result{}
+}
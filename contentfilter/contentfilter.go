@@ -0,0 +1,96 @@
+// Package contentfilter screens user-written text before it is stored.
+// Every registered Checker looks at the text and the strictest verdict wins:
+// content is allowed, held for a moderator, or rejected with a reason.
+//
+// Built-in checkers cover banned words (BANNED_WORDS, with leetspeak
+// folded away), link density and repeat posting. More can be added with
+// Register.
+package contentfilter
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// Verdict is a checker's decision. Higher values are stricter.
+type Verdict int
+
+const (
+	Allow Verdict = iota
+	Hold
+	Reject
+)
+
+func (v Verdict) String() string {
+	switch v {
+	case Hold:
+		return "hold"
+	case Reject:
+		return "reject"
+	}
+	return "allow"
+}
+
+// Input is the text being written and who is writing it. EntityType is the
+// kind of content: comment, review, recipe, message or farm.
+type Input struct {
+	UserID     string
+	EntityType string
+	Text       string
+}
+
+// Result is the outcome of a check. Reason is shown to the author on
+// rejection and to moderators on a hold.
+type Result struct {
+	Verdict Verdict
+	Reason  string
+	Checker string
+}
+
+// Checker inspects content. Implementations must be safe for concurrent use
+// and should return Allow when they have nothing to say.
+type Checker interface {
+	Name() string
+	Check(ctx context.Context, in Input) Result
+}
+
+var (
+	mu       sync.RWMutex
+	checkers = []Checker{
+		newBannedWords(),
+		linkDensity{},
+		repeatPosting{},
+	}
+)
+
+// Register adds a checker to the pipeline.
+func Register(c Checker) {
+	mu.Lock()
+	defer mu.Unlock()
+	checkers = append(checkers, c)
+}
+
+// Check runs every checker and returns the strictest result. It stops early
+// on a rejection.
+func Check(ctx context.Context, in Input) Result {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := Result{Verdict: Allow}
+	for _, c := range checkers {
+		res := c.Check(ctx, in)
+		if res.Verdict <= result.Verdict {
+			continue
+		}
+		res.Checker = c.Name()
+		result = res
+		if result.Verdict == Reject {
+			break
+		}
+	}
+	if result.Verdict != Allow {
+		log.Printf("Content filter: %s %s by %s (%s: %s)", result.Verdict, in.EntityType, in.UserID, result.Checker, result.Reason)
+	}
+	return result
+}
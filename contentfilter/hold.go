@@ -0,0 +1,74 @@
+package contentfilter
+
+import (
+	"context"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HoldForReview queues held content for moderators by filing a report from
+// models.SystemReporter. If the caller stored the content hidden, the report
+// also records a "hide" action by the system, so a moderator publishes the
+// content by reverting that action like any other.
+func HoldForReview(ctx context.Context, targetType, targetID, authorID string, res Result, hidden bool) error {
+	return HoldForReviewAt(ctx, time.Now(), targetType, targetID, authorID, res, hidden)
+}
+
+// HoldForReviewAt is HoldForReview with the time of the hold given, for
+// callers that must stamp the hidden content with the same time as the
+// "hide" action.
+func HoldForReviewAt(ctx context.Context, at time.Time, targetType, targetID, authorID string, res Result, hidden bool) error {
+	now := at.UTC().Truncate(time.Millisecond)
+	filter := bson.M{
+		"reportedBy": models.SystemReporter,
+		"targetType": targetType,
+		"targetId":   targetID,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"reason":    "Held by content filter (" + res.Checker + ")",
+			"notes":     res.Reason,
+			"status":    "pending",
+			"updatedAt": now,
+		},
+		"$setOnInsert": bson.M{"createdAt": now, "notified": false},
+	}
+
+	if hidden && !heldHidden(ctx, filter) {
+		update["$push"] = bson.M{"actions": models.ModerationAction{
+			ID:          primitive.NewObjectID(),
+			Action:      "hide",
+			TargetType:  targetType,
+			TargetID:    targetID,
+			UserID:      authorID,
+			ModeratorID: models.SystemReporter,
+			Notes:       res.Reason,
+			CreatedAt:   now,
+		}}
+	}
+
+	_, err := db.ReportsCollection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	return err
+}
+
+// heldHidden reports whether an earlier hold already hid the content and
+// has not been reverted, so edits don't stack hide actions.
+func heldHidden(ctx context.Context, filter bson.M) bool {
+	f := bson.M{"actions": bson.M{"$elemMatch": bson.M{
+		"action":      "hide",
+		"moderatorId": models.SystemReporter,
+		"revertedAt":  nil,
+	}}}
+	for k, v := range filter {
+		f[k] = v
+	}
+	n, err := db.ReportsCollection.CountDocuments(ctx, f)
+	return err == nil && n > 0
+}
@@ -16,25 +16,37 @@ import (
 var (
 	Client *mongo.Client
 	// Your collections:
-	AnalyticsCollection  *mongo.Collection
-	CartCollection       *mongo.Collection
-	OrderCollection      *mongo.Collection
-	CatalogueCollection  *mongo.Collection
-	FarmsCollection      *mongo.Collection
-	FarmOrdersCollection *mongo.Collection
-	CropsCollection      *mongo.Collection
-	CommentsCollection   *mongo.Collection
-	UserCollection       *mongo.Collection
-	ProductCollection    *mongo.Collection
-	UserDataCollection   *mongo.Collection
-	ReviewsCollection    *mongo.Collection
-	SettingsCollection   *mongo.Collection
-	FollowingsCollection *mongo.Collection
-	ActivitiesCollection *mongo.Collection
-	ChatsCollection      *mongo.Collection
-	MessagesCollection   *mongo.Collection
-	ReportsCollection    *mongo.Collection
-	RecipeCollection     *mongo.Collection
+	AnalyticsCollection         *mongo.Collection
+	APITokensCollection         *mongo.Collection
+	CartCollection              *mongo.Collection
+	OrderCollection             *mongo.Collection
+	CatalogueCollection         *mongo.Collection
+	FarmsCollection             *mongo.Collection
+	FarmOrdersCollection        *mongo.Collection
+	FarmVerificationsCollection *mongo.Collection
+	CropWatchesCollection       *mongo.Collection
+	CropsCollection             *mongo.Collection
+	DataExportsCollection       *mongo.Collection
+	CommentsCollection          *mongo.Collection
+	UserCollection              *mongo.Collection
+	ProductCollection           *mongo.Collection
+	UserDataCollection          *mongo.Collection
+	ReviewsCollection           *mongo.Collection
+	ReviewVotesCollection       *mongo.Collection
+	RatingsCollection           *mongo.Collection
+	SettingsCollection          *mongo.Collection
+	FollowingsCollection        *mongo.Collection
+	ActivitiesCollection        *mongo.Collection
+	ChatsCollection             *mongo.Collection
+	MessagesCollection          *mongo.Collection
+	NotificationsCollection     *mongo.Collection
+	ReportsCollection           *mongo.Collection
+	RecipeCollection            *mongo.Collection
+	ReactionsCollection         *mongo.Collection
+	RefreshTokensCollection     *mongo.Collection
+	SessionsCollection          *mongo.Collection
+	SecurityEventsCollection    *mongo.Collection
+	BlocksCollection            *mongo.Collection
 )
 
 // limiter chan to cap concurrent Mongo ops
@@ -82,22 +94,34 @@ func init() {
 
 	// Initialize your collections
 	db := Client.Database("eventdb")
+	APITokensCollection = db.Collection("apitokens")
 	ActivitiesCollection = db.Collection("activities")
 	AnalyticsCollection = db.Collection("analytics")
+	BlocksCollection = db.Collection("blocks")
 	CartCollection = db.Collection("cart")
 	CatalogueCollection = db.Collection("catalogue")
 	ChatsCollection = db.Collection("chats")
 	CommentsCollection = db.Collection("comments")
 	CropsCollection = db.Collection("crops")
+	CropWatchesCollection = db.Collection("cropwatches")
+	DataExportsCollection = db.Collection("dataexports")
 	FarmsCollection = db.Collection("farms")
 	FollowingsCollection = db.Collection("followings")
 	FarmOrdersCollection = db.Collection("forders")
+	FarmVerificationsCollection = db.Collection("farmverifications")
 	MessagesCollection = db.Collection("messages")
+	NotificationsCollection = db.Collection("notifications")
 	OrderCollection = db.Collection("orders")
 	ProductCollection = db.Collection("products")
+	RatingsCollection = db.Collection("ratings")
+	ReactionsCollection = db.Collection("reactions")
 	RecipeCollection = db.Collection("recipes")
+	RefreshTokensCollection = db.Collection("refreshtokens")
 	ReportsCollection = db.Collection("reports")
 	ReviewsCollection = db.Collection("reviews")
+	ReviewVotesCollection = db.Collection("reviewvotes")
+	SecurityEventsCollection = db.Collection("securityevents")
+	SessionsCollection = db.Collection("sessions")
 	SettingsCollection = db.Collection("settings")
 	UserDataCollection = db.Collection("userdata")
 	UserCollection = db.Collection("users")
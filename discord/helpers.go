@@ -5,9 +5,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 
+	"naevis/blocks"
+	"naevis/contentfilter"
 	"naevis/db"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -44,8 +48,17 @@ type Message struct {
 	Deleted   bool       `bson:"deleted"           json:"deleted"`
 	ReadBy    []string   `bson:"readBy,omitempty"  json:"readBy,omitempty"`
 	Status    string     `bson:"status,omitempty"  json:"status,omitempty"` // e.g. "sent", "read"
+	Hidden    bool       `bson:"hidden,omitempty"  json:"hidden,omitempty"` // held by the content filter
 }
 
+var (
+	// errRejected wraps the content filter's reason for refusing a message.
+	errRejected = errors.New("message rejected")
+	// errBlocked is returned when the sender and another participant have
+	// blocked one another.
+	errBlocked = errors.New("blocked by a participant")
+)
+
 func persistMediaMessage(chatID primitive.ObjectID, sender, mediaURL, mediaType string) (*Message, error) {
 	return persistMessage(chatID, sender, "", mediaURL, mediaType)
 }
@@ -63,12 +76,26 @@ func persistMessage(chatID primitive.ObjectID, sender, content string, mediaURL
 		}
 	}
 
+	var chat Chat
+	if err := db.ChatsCollection.FindOne(ctx, bson.M{"_id": chatID}).Decode(&chat); err != nil {
+		return nil, err
+	}
+	if blocks.AnyBlocked(ctx, sender, chat.Participants) {
+		return nil, errBlocked
+	}
+
+	verdict := contentfilter.Check(ctx, contentfilter.Input{UserID: sender, EntityType: "message", Text: content})
+	if verdict.Verdict == contentfilter.Reject {
+		return nil, fmt.Errorf("%w: %s", errRejected, verdict.Reason)
+	}
+
 	msg := &Message{
 		ChatID:    chatID,
 		Sender:    sender,
 		Content:   content,
 		Media:     media,
 		CreatedAt: time.Now(),
+		Hidden:    verdict.Verdict == contentfilter.Hold,
 	}
 
 	res, err := db.MessagesCollection.InsertOne(ctx, msg)
@@ -76,6 +103,11 @@ func persistMessage(chatID primitive.ObjectID, sender, content string, mediaURL
 		return nil, err
 	}
 	msg.ID = res.InsertedID.(primitive.ObjectID)
+	if msg.Hidden {
+		if err := contentfilter.HoldForReview(ctx, "message", msg.ID.Hex(), sender, verdict, true); err != nil {
+			log.Printf("Failed to queue held message %s: %v", msg.ID.Hex(), err)
+		}
+	}
 
 	// Update chat timestamp
 	db.ChatsCollection.UpdateOne(ctx,
@@ -3,14 +3,18 @@ package discord
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"naevis/blocks"
+	"naevis/contentfilter"
 	"naevis/db"
 	"naevis/utils"
 
@@ -184,6 +188,10 @@ func StartNewChat(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		http.Error(w, "must include yourself", 400)
 		return
 	}
+	if blocks.AnyBlocked(r.Context(), user, body.Participants) {
+		http.Error(w, "cannot start a chat with a blocked user", http.StatusForbidden)
+		return
+	}
 	// check existing chat
 	filter := bson.M{"participants": bson.M{"$all": body.Participants}}
 	var existing Chat
@@ -250,7 +258,7 @@ func GetChatMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 	}
 
 	opts := options.Find().SetSort(bson.M{"createdAt": 1}).SetLimit(limit).SetSkip(skip)
-	cursor, err := db.MessagesCollection.Find(ctx, bson.M{"chatId": chatID}, opts)
+	cursor, err := db.MessagesCollection.Find(ctx, bson.M{"chatId": chatID, "hidden": bson.M{"$ne": true}}, opts)
 	if err != nil {
 		http.Error(w, err.Error(), 500)
 		return
@@ -285,6 +293,18 @@ func SendMessageREST(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 
 	sender := utils.GetUserIDFromRequest(r)
 	msg, err := persistMessage(chatID, sender, body.Content, "", "")
+	if errors.Is(err, errRejected) {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if errors.Is(err, errBlocked) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, "chat not found", http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -305,15 +325,30 @@ func EditMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		http.Error(w, "invalid body", 400)
 		return
 	}
+	sender := utils.GetUserIDFromRequest(r)
+	verdict := contentfilter.Check(ctx, contentfilter.Input{UserID: sender, EntityType: "message", Text: body.Content})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "message rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
 	now := time.Now()
+	set := bson.M{"content": body.Content, "editedAt": now}
+	if verdict.Verdict == contentfilter.Hold {
+		set["hidden"] = true
+	}
 	res, err := db.MessagesCollection.UpdateOne(ctx,
 		bson.M{"_id": msgID},
-		bson.M{"$set": bson.M{"content": body.Content, "editedAt": now}},
+		bson.M{"$set": set},
 	)
 	if err != nil || res.MatchedCount == 0 {
 		http.Error(w, "not found or no permission", 404)
 		return
 	}
+	if verdict.Verdict == contentfilter.Hold {
+		if err := contentfilter.HoldForReview(ctx, "message", msgID.Hex(), sender, verdict, true); err != nil {
+			log.Printf("Failed to queue held message %s: %v", msgID.Hex(), err)
+		}
+	}
 	w.WriteHeader(204)
 }
 
@@ -356,7 +391,7 @@ func SearchMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 		}
 	}
 
-	filter := bson.M{"chatId": chatID}
+	filter := bson.M{"chatId": chatID, "hidden": bson.M{"$ne": true}}
 	if term != "" {
 		filter["content"] = bson.M{"$regex": primitive.Regex{Pattern: term, Options: "i"}}
 	}
@@ -91,6 +91,10 @@ func HandleWebSocket(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 				log.Printf("Failed to persist message from %s: %v", userID, err)
 				break
 			}
+			if msg.Hidden {
+				// Held messages only reach others once a moderator releases them.
+				break
+			}
 
 			broadcastToChat(in.ChatID, map[string]interface{}{
 				"type":    "message",
@@ -39,6 +39,7 @@ func BuyCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	// Decrement quantity
 	filter := bson.M{
 		"_id":              farmID,
+		"deletedAt":        nil,
 		"crops._id":        cropID,
 		"crops.quantity":   bson.M{"$gt": 0},
 		"crops.outOfStock": false,
@@ -188,13 +189,26 @@ func GetIncomingFarmOrders(w http.ResponseWriter, r *http.Request, ps httprouter
 // 	_ = orderID
 // }
 
-func updateOrderStatus(w http.ResponseWriter, orderID string, newStatus string) {
+// updateOrderStatus moves a farm order to newStatus. Only the owner of the
+// farm the order was placed with may do so, even once the farm is deleted,
+// so its open orders can still be closed.
+func updateOrderStatus(w http.ResponseWriter, r *http.Request, orderID string, newStatus string) {
 	objID, err := primitive.ObjectIDFromHex(orderID)
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid order ID"})
 		return
 	}
 
+	var order models.FarmOrder
+	if err := db.FarmOrdersCollection.FindOne(r.Context(), bson.M{"_id": objID}).Decode(&order); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Order not found"})
+		return
+	}
+	if !isFarmOwner(r, order.FarmID) {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Not your farm's order"})
+		return
+	}
+
 	res, err := db.FarmOrdersCollection.UpdateOne(context.Background(),
 		bson.M{"_id": objID},
 		bson.M{"$set": bson.M{"status": newStatus}},
@@ -208,20 +222,30 @@ func updateOrderStatus(w http.ResponseWriter, orderID string, newStatus string)
 	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "status": newStatus})
 }
 
+// isFarmOwner reports whether the caller created farmID, deleted or not.
+func isFarmOwner(r *http.Request, farmID primitive.ObjectID) bool {
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		return false
+	}
+	n, err := db.FarmsCollection.CountDocuments(r.Context(), bson.M{"_id": farmID, "createdBy": userID})
+	return err == nil && n > 0
+}
+
 func AcceptOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	updateOrderStatus(w, ps.ByName("id"), "accepted")
+	updateOrderStatus(w, r, ps.ByName("id"), "accepted")
 }
 
 func RejectOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	updateOrderStatus(w, ps.ByName("id"), "rejected")
+	updateOrderStatus(w, r, ps.ByName("id"), "rejected")
 }
 
 func MarkOrderDelivered(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	updateOrderStatus(w, ps.ByName("id"), "delivered")
+	updateOrderStatus(w, r, ps.ByName("id"), "delivered")
 }
 
 func MarkOrderPaid(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	updateOrderStatus(w, ps.ByName("id"), "paid")
+	updateOrderStatus(w, r, ps.ByName("id"), "paid")
 }
 
 // GET /api/v1/farmorders/:id/receipt
@@ -239,6 +263,11 @@ func DownloadReceipt(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Order not found"})
 		return
 	}
+	// Only the buyer and the farm see a receipt
+	if userID, _ := getUserIDFromContext(r); order.UserID.Hex() != userID && !isFarmOwner(r, order.FarmID) {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Order not found"})
+		return
+	}
 
 	utils.RespondWithJSON(w, http.StatusOK, utils.M{
 		"success": true,
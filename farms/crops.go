@@ -30,6 +30,26 @@ func getUserIDFromContext(r *http.Request) (string, bool) {
 	return userID, ok
 }
 
+// ownFarm loads the live farm farmID and checks the caller created it. On
+// failure it has already replied and returns false.
+func ownFarm(w http.ResponseWriter, r *http.Request, farmID primitive.ObjectID) (models.Farm, bool) {
+	var farm models.Farm
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return farm, false
+	}
+	if err := db.FarmsCollection.FindOne(r.Context(), bson.M{"_id": farmID, "deletedAt": nil}).Decode(&farm); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Farm not found"})
+		return farm, false
+	}
+	if farm.CreatedBy != userID {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Not your farm"})
+		return farm, false
+	}
+	return farm, true
+}
+
 func handleImageUpload(r *http.Request, fieldName, dir string) (string, error) {
 	file, header, err := r.FormFile(fieldName)
 	if err != nil {
@@ -86,8 +106,7 @@ func AddCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	if _, ok := getUserIDFromContext(r); !ok {
-		http.Error(w, "Invalid user", http.StatusBadRequest)
+	if _, ok := ownFarm(w, r, farmID); !ok {
 		return
 	}
 
@@ -115,18 +134,24 @@ func AddCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	go notifyCropWatchers(nil, crop)
+
 	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "cropId": crop.ID.Hex()})
 }
 
 func EditCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
 	cropID, err := primitive.ObjectIDFromHex(ps.ByName("cropid"))
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid crop ID"})
 		return
 	}
 
-	if _, ok := getUserIDFromContext(r); !ok {
-		http.Error(w, "Invalid user", http.StatusBadRequest)
+	if _, ok := ownFarm(w, r, farmID); !ok {
 		return
 	}
 
@@ -154,38 +179,65 @@ func EditCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		update["imageUrl"] = imageURL
 	}
 
-	_, err = db.CropsCollection.UpdateOne(context.Background(), bson.M{"_id": cropID}, bson.M{"$set": update})
+	// FindOneAndUpdate hands back the previous listing so watchers can be told about restocks and price drops.
+	var before models.Crop
+	err = db.CropsCollection.FindOneAndUpdate(context.Background(), bson.M{"_id": cropID, "farmId": farmID, "deletedAt": nil}, bson.M{"$set": update}).Decode(&before)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Crop not found"})
+		return
+	}
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false})
 		return
 	}
 
+	after := before
+	after.Name = update["name"].(string)
+	after.Unit = update["unit"].(string)
+	after.Price = update["price"].(float64)
+	after.Quantity = update["quantity"].(int)
+	after.OutOfStock = update["outOfStock"].(bool)
+	go notifyCropWatchers(&before, after)
+
 	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
 }
 
 func DeleteCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
 	cropID, err := primitive.ObjectIDFromHex(ps.ByName("cropid"))
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid crop ID"})
 		return
 	}
 
-	if _, ok := getUserIDFromContext(r); !ok {
-		http.Error(w, "Invalid user", http.StatusBadRequest)
+	if _, ok := ownFarm(w, r, farmID); !ok {
 		return
 	}
 
-	res, err := db.CropsCollection.DeleteOne(context.Background(), bson.M{"_id": cropID})
-	if err != nil || res.DeletedCount == 0 {
+	// PurgeDeletedFarms removes it once FarmRestoreWindow has passed
+	now := time.Now()
+	res, err := db.CropsCollection.UpdateOne(context.Background(),
+		bson.M{"_id": cropID, "farmId": farmID, "deletedAt": nil},
+		bson.M{"$set": bson.M{"deletedAt": now}},
+	)
+	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to delete crop"})
 		return
 	}
+	if res.ModifiedCount == 0 {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Crop not found"})
+		return
+	}
 
-	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "restorableUntil": now.Add(FarmRestoreWindow)})
 }
 
 func GetFilteredCrops(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	query := bson.M{}
+	query := bson.M{"deletedAt": nil}
 	params := r.URL.Query()
 
 	if category := params.Get("category"); category != "" {
@@ -308,7 +360,7 @@ func GetCropCatalogue(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	cursor, err := db.CropsCollection.Find(ctx, bson.M{})
+	cursor, err := db.CropsCollection.Find(ctx, bson.M{"deletedAt": nil})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to fetch crop catalogue"})
 		return
@@ -336,7 +388,7 @@ func GetCropCatalogue(w http.ResponseWriter, r *http.Request, _ httprouter.Param
 
 func GetCropTypes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: bson.M{}}}, // No filters, return all types
+		{{Key: "$match", Value: bson.M{"deletedAt": nil}}}, // Every live crop, all types
 
 		{{
 			Key: "$group", Value: bson.M{
@@ -20,13 +20,13 @@ func GetFarmDash(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	userid := utils.GetUserIDFromRequest(r)
 
 	var farm models.Farm
-	if err := db.FarmsCollection.FindOne(context.Background(), bson.M{"createdBy": userid}).Decode(&farm); err != nil {
+	if err := db.FarmsCollection.FindOne(context.Background(), bson.M{"createdBy": userid, "deletedAt": nil}).Decode(&farm); err != nil {
 		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Farm not found"})
 		return
 	}
 
 	// Fetch crops from separate crops collection
-	cursor, err := db.CropsCollection.Find(context.Background(), bson.M{"farmId": farm.FarmID})
+	cursor, err := db.CropsCollection.Find(context.Background(), bson.M{"farmId": farm.FarmID, "deletedAt": nil})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to load crops"})
 		return
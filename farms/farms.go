@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
 	"os"
 	"regexp"
@@ -13,8 +14,10 @@ import (
 	"strings"
 	"time"
 
+	"naevis/contentfilter"
 	"naevis/db"
 	"naevis/globals"
+	"naevis/middleware"
 	"naevis/models"
 	"naevis/mq"
 	"naevis/utils"
@@ -57,6 +60,12 @@ func CreateFarm(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		return
 	}
 
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{UserID: requestingUserID, EntityType: "farm", Text: farm.Name + "\n" + farm.Description})
+	if verdict.Verdict == contentfilter.Reject {
+		utils.RespondWithJSON(w, http.StatusUnprocessableEntity, utils.M{"success": false, "message": "Farm rejected: " + verdict.Reason})
+		return
+	}
+
 	if path, err := handleFarmPhotoUpload(r, farm.FarmID); err == nil {
 		farm.Photo = path
 	}
@@ -66,8 +75,21 @@ func CreateFarm(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to insert farm"})
 		return
 	}
+	holdFarmForReview(r.Context(), farm.FarmID, requestingUserID, verdict)
 
-	go mq.Emit("farm-created", mq.Index{EntityType: "farm", EntityId: farm.FarmID.String(), Method: "POST"})
+	_, err = db.UserCollection.UpdateOne(context.Background(),
+		bson.M{"userid": requestingUserID},
+		bson.M{"$addToSet": bson.M{"role": middleware.RoleFarmer}},
+	)
+	if err != nil {
+		log.Printf("CreateFarm: failed to grant farmer role to %s: %v", requestingUserID, err)
+	}
+
+	if verdict.Verdict == contentfilter.Hold {
+		utils.RespondWithJSON(w, http.StatusAccepted, utils.M{"success": true, "id": farm.FarmID.Hex(), "status": "held"})
+		return
+	}
+	go mq.Emit("farm-created", mq.Index{EntityType: "farm", EntityId: farm.FarmID.Hex(), Method: "POST"})
 
 	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "id": farm.FarmID.Hex()})
 }
@@ -80,13 +102,13 @@ func GetFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	}
 
 	var farm models.Farm
-	if err := db.FarmsCollection.FindOne(context.Background(), bson.M{"_id": id}).Decode(&farm); err != nil {
+	if err := db.FarmsCollection.FindOne(context.Background(), bson.M{"_id": id, "deletedAt": nil}).Decode(&farm); err != nil {
 		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Farm not found"})
 		return
 	}
 
 	// Fetch crops from separate crops collection
-	cursor, err := db.CropsCollection.Find(context.Background(), bson.M{"farmId": id})
+	cursor, err := db.CropsCollection.Find(context.Background(), bson.M{"farmId": id, "deletedAt": nil})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to load crops"})
 		return
@@ -101,9 +123,12 @@ func GetFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	farm.Crops = crops
 
+	userID, _ := getUserIDFromContext(r)
+
 	utils.RespondWithJSON(w, http.StatusOK, utils.M{
-		"success": true,
-		"farm":    farm,
+		"success":    true,
+		"farm":       farm,
+		"isFavorite": isFavoriteFarm(r, userID, id),
 	})
 }
 func handleFarmPhotoUpload(r *http.Request, farmID primitive.ObjectID) (string, error) {
@@ -142,7 +167,6 @@ func EditFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	_ = requestingUserID
 	updateFields := bson.M{}
 	contentType := r.Header.Get("Content-Type")
 
@@ -198,17 +222,60 @@ func EditFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{UserID: requestingUserID, EntityType: "farm", Text: input.Name + "\n" + input.Description})
+	if verdict.Verdict == contentfilter.Reject {
+		utils.RespondWithJSON(w, http.StatusUnprocessableEntity, utils.M{"success": false, "message": "Farm rejected: " + verdict.Reason})
+		return
+	}
+
 	updateFields["updatedAt"] = time.Now()
 
-	_, err = db.FarmsCollection.UpdateOne(r.Context(), bson.M{"_id": farmID}, bson.M{"$set": updateFields})
+	_, err = db.FarmsCollection.UpdateOne(r.Context(), bson.M{"_id": farmID, "deletedAt": nil}, bson.M{"$set": updateFields})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Database error"})
 		return
 	}
+	holdFarmForReview(r.Context(), farmID, requestingUserID, verdict)
+	if verdict.Verdict == contentfilter.Hold {
+		utils.RespondWithJSON(w, http.StatusAccepted, utils.M{"success": true, "message": "Farm held for review", "status": "held"})
+		return
+	}
 
 	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "message": "Farm updated"})
 }
 
+// holdFarmForReview takes down a farm the content filter held, the way a
+// moderator's hide would: the farm and its crops are soft-deleted under a
+// "hide" hold, and the report records the hide with the same time so that
+// reverting it brings them back.
+func holdFarmForReview(ctx context.Context, farmID primitive.ObjectID, userID string, verdict contentfilter.Result) {
+	if verdict.Verdict != contentfilter.Hold {
+		return
+	}
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	res, err := db.FarmsCollection.UpdateOne(ctx,
+		bson.M{"_id": farmID, "deletedAt": nil},
+		bson.M{"$set": bson.M{"deletedAt": now, "moderationHold": "hide"}},
+	)
+	if err != nil {
+		log.Printf("Failed to hide held farm %s: %v", farmID.Hex(), err)
+		return
+	}
+	if res.ModifiedCount > 0 {
+		_, err = db.CropsCollection.UpdateMany(ctx,
+			bson.M{"farmId": farmID, "deletedAt": nil},
+			bson.M{"$set": bson.M{"deletedAt": now}},
+		)
+		if err != nil {
+			log.Printf("Failed to hide crops of held farm %s: %v", farmID.Hex(), err)
+		}
+		go mq.Emit("farm-deleted", mq.Index{EntityType: "farm", EntityId: farmID.Hex(), Method: "DELETE"})
+	}
+	if err := contentfilter.HoldForReviewAt(ctx, now, "farm", farmID.Hex(), userID, verdict, true); err != nil {
+		log.Printf("Failed to queue held farm %s: %v", farmID.Hex(), err)
+	}
+}
+
 func DeleteFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
 	if err != nil {
@@ -222,25 +289,42 @@ func DeleteFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		http.Error(w, "Invalid user", http.StatusBadRequest)
 		return
 	}
-	_ = requestingUserID
 
 	var farm models.Farm
-	if err := db.FarmsCollection.FindOne(context.Background(), bson.M{"_id": farmID}).Decode(&farm); err != nil {
+	if err := db.FarmsCollection.FindOne(context.Background(), bson.M{"_id": farmID, "deletedAt": nil}).Decode(&farm); err != nil {
 		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Not found"})
 		return
 	}
 
-	_, err = db.FarmsCollection.DeleteOne(context.Background(), bson.M{"_id": farmID})
+	if farm.CreatedBy != requestingUserID {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Not your farm"})
+		return
+	}
+
+	// Soft-delete the farm and its crops with the same timestamp so a restore
+	// can tell which crops went down with it. PurgeDeletedFarms removes them
+	// for good once FarmRestoreWindow has passed.
+	now := time.Now()
+	_, err = db.FarmsCollection.UpdateOne(context.Background(), bson.M{"_id": farmID}, bson.M{
+		"$set": bson.M{"deletedAt": now, "updatedAt": now},
+	})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false})
 		return
 	}
 
-	if farm.Photo != "" {
-		_ = os.Remove("." + farm.Photo)
+	_, err = db.CropsCollection.UpdateMany(context.Background(),
+		bson.M{"farmId": farmID, "deletedAt": nil},
+		bson.M{"$set": bson.M{"deletedAt": now}},
+	)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to delete crops"})
+		return
 	}
 
-	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+	go mq.Emit("farm-deleted", mq.Index{EntityType: "farm", EntityId: farmID.Hex(), Method: "DELETE"})
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "restorableUntil": now.Add(FarmRestoreWindow)})
 }
 func GetCropFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -274,7 +358,7 @@ func GetCropFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	breedFilter := strings.ToLower(r.URL.Query().Get("breed"))
 
 	// Find crops by ID
-	cursor, err := db.CropsCollection.Find(ctx, bson.M{"_id": cropID})
+	cursor, err := db.CropsCollection.Find(ctx, bson.M{"_id": cropID, "deletedAt": nil})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to fetch crop data"})
 		return
@@ -295,7 +379,7 @@ func GetCropFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	for i, crop := range cropInstances {
 		farmIDs[i] = crop.FarmID
 	}
-	farmCursor, err := db.FarmsCollection.Find(ctx, bson.M{"_id": bson.M{"$in": farmIDs}})
+	farmCursor, err := db.FarmsCollection.Find(ctx, bson.M{"_id": bson.M{"$in": farmIDs}, "deletedAt": nil})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to fetch farms"})
 		return
@@ -406,9 +490,11 @@ func GetCropTypeFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 	sortBy := r.URL.Query().Get("sortBy")
 	sortOrder := r.URL.Query().Get("sortOrder")
 	breedFilter := strings.ToLower(r.URL.Query().Get("breed"))
+	verifiedOnly := r.URL.Query().Get("verified") == "true"
 
 	filter := bson.M{
-		"name": bson.M{"$regex": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(cropName) + "$", Options: "i"}},
+		"name":      bson.M{"$regex": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(cropName) + "$", Options: "i"}},
+		"deletedAt": nil,
 	}
 	cursor, err := db.CropsCollection.Find(ctx, filter)
 	if err != nil {
@@ -436,7 +522,7 @@ func GetCropTypeFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		farmIDs[i] = crop.FarmID
 	}
 
-	farmCursor, err := db.FarmsCollection.Find(ctx, bson.M{"_id": bson.M{"$in": farmIDs}})
+	farmCursor, err := db.FarmsCollection.Find(ctx, bson.M{"_id": bson.M{"$in": farmIDs}, "deletedAt": nil})
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{
 			"success": false,
@@ -478,7 +564,7 @@ func GetCropTypeFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 		}
 
 		farm, ok := farmMap[crop.FarmID]
-		if !ok {
+		if !ok || (verifiedOnly && !farm.Verified) {
 			continue
 		}
 
@@ -496,6 +582,9 @@ func GetCropTypeFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 			AvailableQtyKg: crop.Quantity,
 			HarvestDate:    harvestDate,
 			Tags:           farm.Tags,
+			Verified:       farm.Verified,
+			AvgRating:      farm.AvgRating,
+			ReviewCount:    farm.ReviewCount,
 		})
 	}
 
@@ -515,6 +604,25 @@ func GetCropTypeFarms(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 			}
 			return listings[i].Breed < listings[j].Breed
 		})
+	case "verified":
+		// Verified farms first, cheapest first within each group
+		sort.SliceStable(listings, func(i, j int) bool {
+			if listings[i].Verified != listings[j].Verified {
+				return listings[i].Verified
+			}
+			return listings[i].PricePerKg < listings[j].PricePerKg
+		})
+	case "rating":
+		// Best-rated farms first; more reviews break ties
+		sort.SliceStable(listings, func(i, j int) bool {
+			if listings[i].AvgRating != listings[j].AvgRating {
+				if sortOrder == "asc" {
+					return listings[i].AvgRating < listings[j].AvgRating
+				}
+				return listings[i].AvgRating > listings[j].AvgRating
+			}
+			return listings[i].ReviewCount > listings[j].ReviewCount
+		})
 	}
 
 	// Pagination
@@ -701,8 +809,25 @@ func GetPaginatedFarms(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 	}
 	skip := (page - 1) * limit
 
+	match := bson.M{"deletedAt": nil}
+	if r.URL.Query().Get("verified") == "true" {
+		match["verified"] = true
+	}
+	if badge := r.URL.Query().Get("badge"); badge != "" {
+		match["badges"] = activeBadgeFilter(badge, time.Now())
+	}
+
+	// boost=verified lists verified farms ahead of the rest
+	sortStage := bson.D{{Key: "updatedAt", Value: -1}}
+	if r.URL.Query().Get("sort") == "rating" {
+		sortStage = bson.D{{Key: "avgRating", Value: -1}, {Key: "reviewCount", Value: -1}, {Key: "updatedAt", Value: -1}}
+	}
+	if r.URL.Query().Get("boost") == "verified" {
+		sortStage = append(bson.D{{Key: "verified", Value: -1}}, sortStage...)
+	}
+
 	// Count total farms for pagination metadata
-	total, err := db.FarmsCollection.CountDocuments(ctx, bson.M{})
+	total, err := db.FarmsCollection.CountDocuments(ctx, match)
 	if err != nil {
 		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to count farms"})
 		return
@@ -710,7 +835,8 @@ func GetPaginatedFarms(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 
 	// Aggregation with $lookup to join crops into farms
 	pipeline := mongo.Pipeline{
-		{{Key: "$sort", Value: bson.D{{Key: "updatedAt", Value: -1}}}},
+		{{Key: "$match", Value: match}},
+		{{Key: "$sort", Value: sortStage}},
 		{{Key: "$skip", Value: int64(skip)}},
 		{{Key: "$limit", Value: int64(limit)}},
 		{{
@@ -719,6 +845,9 @@ func GetPaginatedFarms(w http.ResponseWriter, r *http.Request, _ httprouter.Para
 				{Key: "from", Value: "crops"},
 				{Key: "localField", Value: "_id"},
 				{Key: "foreignField", Value: "farmId"},
+				{Key: "pipeline", Value: mongo.Pipeline{
+					{{Key: "$match", Value: bson.M{"deletedAt": nil}}},
+				}},
 				{Key: "as", Value: "crops"},
 			},
 		}},
@@ -0,0 +1,162 @@
+package farms
+
+import (
+	"net/http"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// favoriteFilter identifies a user's favorite record for a farm in the
+// userdata collection.
+func favoriteFilter(userID string, farmID primitive.ObjectID) bson.M {
+	return bson.M{
+		"userid":      userID,
+		"entity_type": "farm",
+		"entity_id":   farmID.Hex(),
+		"item_type":   "favourite",
+	}
+}
+
+func isFavoriteFarm(r *http.Request, userID string, farmID primitive.ObjectID) bool {
+	if userID == "" {
+		return false
+	}
+	n, err := db.UserDataCollection.CountDocuments(r.Context(), favoriteFilter(userID, farmID))
+	return err == nil && n > 0
+}
+
+// PUT /api/v1/farms/:id/favorite
+//
+// Idempotent: favoriting twice does not bump the counter again.
+func FavoriteFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
+
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	count, err := db.FarmsCollection.CountDocuments(r.Context(), bson.M{"_id": farmID, "deletedAt": nil})
+	if err != nil || count == 0 {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Farm not found"})
+		return
+	}
+
+	res, err := db.UserDataCollection.UpdateOne(r.Context(),
+		favoriteFilter(userID, farmID),
+		bson.M{"$setOnInsert": bson.M{
+			"item_id":    farmID.Hex(),
+			"created_at": time.Now().Format(time.RFC3339),
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to favorite farm"})
+		return
+	}
+
+	if res.UpsertedCount == 1 {
+		if _, err := db.FarmsCollection.UpdateOne(r.Context(), bson.M{"_id": farmID}, bson.M{"$inc": bson.M{"favoritesCount": 1}}); err != nil {
+			utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to update favorites count"})
+			return
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "isFavorite": true})
+}
+
+// DELETE /api/v1/farms/:id/favorite
+func UnfavoriteFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
+
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	res, err := db.UserDataCollection.DeleteOne(r.Context(), favoriteFilter(userID, farmID))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to unfavorite farm"})
+		return
+	}
+
+	if res.DeletedCount == 1 {
+		_, err = db.FarmsCollection.UpdateOne(r.Context(),
+			bson.M{"_id": farmID, "favoritesCount": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"favoritesCount": -1}},
+		)
+		if err != nil {
+			utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to update favorites count"})
+			return
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "isFavorite": false})
+}
+
+// GET /api/v1/favorites/farms
+func GetFavoriteFarms(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	cursor, err := db.UserDataCollection.Find(r.Context(), bson.M{
+		"userid":      userID,
+		"entity_type": "farm",
+		"item_type":   "favourite",
+	})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to fetch favorites"})
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	var farmIDs []primitive.ObjectID
+	for cursor.Next(r.Context()) {
+		var fav struct {
+			EntityID string `bson:"entity_id"`
+		}
+		if err := cursor.Decode(&fav); err != nil {
+			continue
+		}
+		if oid, err := primitive.ObjectIDFromHex(fav.EntityID); err == nil {
+			farmIDs = append(farmIDs, oid)
+		}
+	}
+
+	farms := []models.Farm{}
+	if len(farmIDs) > 0 {
+		farmCursor, err := db.FarmsCollection.Find(r.Context(), bson.M{"_id": bson.M{"$in": farmIDs}, "deletedAt": nil})
+		if err != nil {
+			utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to fetch farms"})
+			return
+		}
+		defer farmCursor.Close(r.Context())
+		if err := farmCursor.All(r.Context(), &farms); err != nil {
+			utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to decode farms"})
+			return
+		}
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "farms": farms})
+}
@@ -0,0 +1,305 @@
+package farms
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/mq"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FarmRestoreWindow is how long a soft-deleted farm can be restored
+// before PurgeDeletedFarms removes it for good.
+const FarmRestoreWindow = 30 * 24 * time.Hour
+
+// closedOrderStatuses are the FarmOrder states that no longer need the farm.
+var closedOrderStatuses = []string{"delivered", "rejected"}
+
+var errOpenOrders = errors.New("still has open orders")
+
+// POST /api/v1/farms/:id/restore
+func RestoreFarm(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
+
+	requestingUserID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	var farm models.Farm
+	filter := bson.M{"_id": farmID, "deletedAt": bson.M{"$ne": nil}}
+	if err := db.FarmsCollection.FindOne(r.Context(), filter).Decode(&farm); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Deleted farm not found"})
+		return
+	}
+
+	if farm.CreatedBy != requestingUserID {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Not your farm"})
+		return
+	}
+
+	if farm.ModerationHold != "" {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Farm was removed by a moderator"})
+		return
+	}
+
+	if time.Since(*farm.DeletedAt) > FarmRestoreWindow {
+		utils.RespondWithJSON(w, http.StatusGone, utils.M{"success": false, "message": "Restore window has expired"})
+		return
+	}
+
+	_, err = db.FarmsCollection.UpdateOne(r.Context(), bson.M{"_id": farmID}, bson.M{
+		"$unset": bson.M{"deletedAt": ""},
+		"$set":   bson.M{"updatedAt": time.Now()},
+	})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to restore farm"})
+		return
+	}
+
+	// Only bring back the crops that went down with the farm; crops deleted
+	// individually before that stay deleted.
+	_, err = db.CropsCollection.UpdateMany(r.Context(),
+		bson.M{"farmId": farmID, "deletedAt": farm.DeletedAt},
+		bson.M{"$unset": bson.M{"deletedAt": ""}},
+	)
+	if err != nil {
+		log.Printf("RestoreFarm: failed to restore crops of %s: %v", farmID.Hex(), err)
+	}
+
+	go mq.Emit("farm-restored", mq.Index{EntityType: "farm", EntityId: farmID.Hex(), Method: "POST"})
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+}
+
+// POST /api/v1/farms/:id/crops/:cropid/restore
+//
+// Brings back a crop deleted on its own within FarmRestoreWindow. Crops
+// that went down with their farm come back with RestoreFarm instead.
+func RestoreCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
+	cropID, err := primitive.ObjectIDFromHex(ps.ByName("cropid"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid crop ID"})
+		return
+	}
+
+	if _, ok := ownFarm(w, r, farmID); !ok {
+		return
+	}
+
+	var crop models.Crop
+	filter := bson.M{"_id": cropID, "farmId": farmID, "deletedAt": bson.M{"$ne": nil}}
+	if err := db.CropsCollection.FindOne(r.Context(), filter).Decode(&crop); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Deleted crop not found"})
+		return
+	}
+
+	if time.Since(*crop.DeletedAt) > FarmRestoreWindow {
+		utils.RespondWithJSON(w, http.StatusGone, utils.M{"success": false, "message": "Restore window has expired"})
+		return
+	}
+
+	_, err = db.CropsCollection.UpdateOne(r.Context(), bson.M{"_id": cropID}, bson.M{
+		"$unset": bson.M{"deletedAt": ""},
+		"$set":   bson.M{"updatedAt": time.Now()},
+	})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to restore crop"})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+}
+
+// PurgeDeletedFarms periodically hard-deletes farms whose restore window has
+// passed, along with their crops, reviews and comments, and crops deleted on
+// their own once their window has passed. A farm or crop with orders that
+// are still open is skipped and retried on the next run.
+func PurgeDeletedFarms() {
+	ticker := time.NewTicker(time.Hour)
+	for range ticker.C {
+		purgeExpiredFarms()
+		purgeExpiredCrops()
+	}
+}
+
+func purgeExpiredFarms() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cutoff := time.Now().Add(-FarmRestoreWindow)
+	// Farms hidden by a moderator or held by the content filter stay until
+	// the action is reverted.
+	cursor, err := db.FarmsCollection.Find(ctx, bson.M{"deletedAt": bson.M{"$lte": cutoff}, "moderationHold": bson.M{"$ne": "hide"}})
+	if err != nil {
+		log.Println("Farm purge find error:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var farms []models.Farm
+	if err := cursor.All(ctx, &farms); err != nil {
+		log.Println("Farm purge decode error:", err)
+		return
+	}
+
+	for _, farm := range farms {
+		if err := purgeFarm(ctx, farm); err != nil {
+			log.Printf("Farm purge skipped %s: %v", farm.FarmID.Hex(), err)
+		}
+	}
+}
+
+func purgeFarm(ctx context.Context, farm models.Farm) error {
+	open, err := db.FarmOrdersCollection.CountDocuments(ctx, bson.M{
+		"farmId": farm.FarmID,
+		"status": bson.M{"$nin": closedOrderStatuses},
+	})
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return errOpenOrders
+	}
+
+	cursor, err := db.CropsCollection.Find(ctx, bson.M{"farmId": farm.FarmID})
+	if err != nil {
+		return err
+	}
+	var crops []models.Crop
+	if err := cursor.All(ctx, &crops); err != nil {
+		return err
+	}
+
+	cropIDs := make([]string, 0, len(crops))
+	for _, crop := range crops {
+		cropIDs = append(cropIDs, crop.ID.Hex())
+		if crop.ImageURL != "" {
+			_ = os.Remove("./static" + crop.ImageURL)
+		}
+	}
+
+	dependents := []bson.M{
+		{"entity_type": "farm", "entity_id": farm.FarmID.Hex()},
+		{"entity_type": "crop", "entity_id": bson.M{"$in": cropIDs}},
+	}
+	if _, err := db.ReviewsCollection.DeleteMany(ctx, bson.M{"$or": dependents}); err != nil {
+		return err
+	}
+	if _, err := db.CommentsCollection.DeleteMany(ctx, bson.M{"$or": dependents}); err != nil {
+		return err
+	}
+	if _, err := db.UserDataCollection.DeleteMany(ctx, bson.M{"$or": dependents}); err != nil {
+		return err
+	}
+	if _, err := db.RatingsCollection.DeleteMany(ctx, bson.M{"$or": dependents}); err != nil {
+		return err
+	}
+	if _, err := db.ReactionsCollection.DeleteMany(ctx, bson.M{"$or": dependents}); err != nil {
+		return err
+	}
+	if _, err := db.CropsCollection.DeleteMany(ctx, bson.M{"farmId": farm.FarmID}); err != nil {
+		return err
+	}
+	if _, err := db.FarmsCollection.DeleteOne(ctx, bson.M{"_id": farm.FarmID}); err != nil {
+		return err
+	}
+
+	if farm.Photo != "" {
+		_ = os.Remove("./static" + farm.Photo)
+	}
+
+	go mq.Emit("farm-purged", mq.Index{EntityType: "farm", EntityId: farm.FarmID.Hex(), Method: "DELETE"})
+	return nil
+}
+
+// purgeExpiredCrops removes crops of live farms deleted longer than
+// FarmRestoreWindow ago. Crops of deleted farms are left to purgeFarm.
+func purgeExpiredCrops() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	cutoff := time.Now().Add(-FarmRestoreWindow)
+	cursor, err := db.CropsCollection.Find(ctx, bson.M{"deletedAt": bson.M{"$lte": cutoff}})
+	if err != nil {
+		log.Println("Crop purge find error:", err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var crops []models.Crop
+	if err := cursor.All(ctx, &crops); err != nil {
+		log.Println("Crop purge decode error:", err)
+		return
+	}
+
+	for _, crop := range crops {
+		live, err := db.FarmsCollection.CountDocuments(ctx, bson.M{"_id": crop.FarmID, "deletedAt": nil})
+		if err != nil || live == 0 {
+			continue
+		}
+		if err := purgeCrop(ctx, crop); err != nil {
+			log.Printf("Crop purge skipped %s: %v", crop.ID.Hex(), err)
+		}
+	}
+}
+
+func purgeCrop(ctx context.Context, crop models.Crop) error {
+	open, err := db.FarmOrdersCollection.CountDocuments(ctx, bson.M{
+		"cropId": crop.ID,
+		"status": bson.M{"$nin": closedOrderStatuses},
+	})
+	if err != nil {
+		return err
+	}
+	if open > 0 {
+		return errOpenOrders
+	}
+
+	dependents := bson.M{"entity_type": "crop", "entity_id": crop.ID.Hex()}
+	if _, err := db.ReviewsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.CommentsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.UserDataCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.RatingsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.ReactionsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.CropsCollection.DeleteOne(ctx, bson.M{"_id": crop.ID}); err != nil {
+		return err
+	}
+
+	if crop.ImageURL != "" {
+		_ = os.Remove("./static" + crop.ImageURL)
+	}
+
+	go mq.Emit("crop-purged", mq.Index{EntityType: "crop", EntityId: crop.ID.Hex(), Method: "DELETE"})
+	return nil
+}
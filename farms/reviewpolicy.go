@@ -0,0 +1,64 @@
+package farms
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PUT /api/v1/farms/:id/review-policy
+//
+// Body: { "verifiedBuyersOnly": true }
+//
+// When enabled, only users with a delivered order from this farm can review
+// the farm or its crops.
+func UpdateReviewPolicy(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
+
+	requestingUserID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		VerifiedBuyersOnly bool `json:"verifiedBuyersOnly"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid JSON body"})
+		return
+	}
+
+	var farm models.Farm
+	if err := db.FarmsCollection.FindOne(r.Context(), bson.M{"_id": farmID, "deletedAt": nil}).Decode(&farm); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Farm not found"})
+		return
+	}
+	if farm.CreatedBy != requestingUserID {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Not your farm"})
+		return
+	}
+
+	_, err = db.FarmsCollection.UpdateOne(r.Context(), bson.M{"_id": farmID}, bson.M{"$set": bson.M{
+		"verifiedBuyersOnly": input.VerifiedBuyersOnly,
+		"updatedAt":          time.Now(),
+	}})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Database error"})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "verifiedBuyersOnly": input.VerifiedBuyersOnly})
+}
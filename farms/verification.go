@@ -0,0 +1,158 @@
+package farms
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxVerificationDocuments = 10
+
+// POST /api/v1/farms/:id/verification
+//
+// Documents are uploaded first through /api/v1/upload/images; the returned
+// URLs are submitted here for an admin to review.
+func SubmitFarmVerification(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
+
+	requestingUserID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		Documents []string `json:"documents"`
+		Badges    []string `json:"badges"`
+		Notes     string   `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid JSON body"})
+		return
+	}
+
+	var documents []string
+	for _, doc := range input.Documents {
+		if doc = strings.TrimSpace(doc); doc != "" {
+			documents = append(documents, doc)
+		}
+	}
+	if len(documents) == 0 || len(documents) > maxVerificationDocuments {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Provide between 1 and 10 documents"})
+		return
+	}
+	for _, badge := range input.Badges {
+		if !models.ValidFarmBadges[badge] {
+			utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Unknown badge: " + badge})
+			return
+		}
+	}
+
+	var farm models.Farm
+	if err := db.FarmsCollection.FindOne(r.Context(), bson.M{"_id": farmID, "deletedAt": nil}).Decode(&farm); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Farm not found"})
+		return
+	}
+	if farm.CreatedBy != requestingUserID {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Not your farm"})
+		return
+	}
+
+	pending, err := db.FarmVerificationsCollection.CountDocuments(r.Context(), bson.M{"farmId": farmID, "status": "pending"})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Database error"})
+		return
+	}
+	if pending > 0 {
+		utils.RespondWithJSON(w, http.StatusConflict, utils.M{"success": false, "message": "A verification request is already pending"})
+		return
+	}
+
+	now := time.Now()
+	request := models.FarmVerification{
+		ID:          primitive.NewObjectID(),
+		FarmID:      farmID,
+		SubmittedBy: requestingUserID,
+		Documents:   documents,
+		Badges:      input.Badges,
+		Notes:       strings.TrimSpace(input.Notes),
+		Status:      "pending",
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if _, err := db.FarmVerificationsCollection.InsertOne(r.Context(), request); err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to submit verification"})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusCreated, utils.M{"success": true, "id": request.ID.Hex()})
+}
+
+// GET /api/v1/farms/:id/verification
+func GetFarmVerification(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	farmID, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid farm ID"})
+		return
+	}
+
+	requestingUserID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	var farm models.Farm
+	if err := db.FarmsCollection.FindOne(r.Context(), bson.M{"_id": farmID, "deletedAt": nil}).Decode(&farm); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Farm not found"})
+		return
+	}
+	if farm.CreatedBy != requestingUserID {
+		utils.RespondWithJSON(w, http.StatusForbidden, utils.M{"success": false, "message": "Not your farm"})
+		return
+	}
+
+	var latest models.FarmVerification
+	opts := options.FindOne().SetSort(bson.M{"createdAt": -1})
+	err = db.FarmVerificationsCollection.FindOne(r.Context(), bson.M{"farmId": farmID}, opts).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "verified": farm.Verified, "request": nil})
+		return
+	} else if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Database error"})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{
+		"success":  true,
+		"verified": farm.Verified,
+		"badges":   farm.Badges,
+		"request":  latest,
+	})
+}
+
+// activeBadgeFilter matches farms holding the given badge that has not expired.
+func activeBadgeFilter(badge string, now time.Time) bson.M {
+	return bson.M{"$elemMatch": bson.M{
+		"type": badge,
+		"$or": []bson.M{
+			{"expiresAt": nil},
+			{"expiresAt": bson.M{"$gt": now}},
+		},
+	}}
+}
@@ -0,0 +1,185 @@
+package farms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/notifications"
+	"naevis/userdata"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// watchNotifyCooldown is the least time between two alerts for one watch,
+// so a listing that flaps in and out of stock doesn't spam its watchers.
+const watchNotifyCooldown = 6 * time.Hour
+
+func normalizeCropName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// GET /api/v1/crops/watchlist
+func GetCropWatchlist(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"createdAt": -1})
+	cursor, err := db.CropWatchesCollection.Find(r.Context(), bson.M{"userId": userID}, opts)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to fetch watchlist"})
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	watches := []models.CropWatch{}
+	if err := cursor.All(r.Context(), &watches); err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to decode watchlist"})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "watchlist": watches})
+}
+
+// POST /api/v1/crops/watchlist
+//
+// Body: { "cropName": "tomato", "targetPrice": 40 }
+//
+// Watching the same crop again updates the target price.
+func WatchCrop(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	var input struct {
+		CropName    string  `json:"cropName"`
+		TargetPrice float64 `json:"targetPrice"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid JSON body"})
+		return
+	}
+
+	cropName := normalizeCropName(input.CropName)
+	if cropName == "" {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Crop name is required"})
+		return
+	}
+	if input.TargetPrice < 0 {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Target price cannot be negative"})
+		return
+	}
+
+	res, err := db.CropWatchesCollection.UpdateOne(r.Context(),
+		bson.M{"userId": userID, "cropName": cropName},
+		bson.M{
+			"$set":         bson.M{"targetPrice": input.TargetPrice},
+			"$setOnInsert": bson.M{"createdAt": time.Now()},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to watch crop"})
+		return
+	}
+	if res.UpsertedCount == 1 {
+		userdata.SetUserData("cropwatch", cropName, userID, "watch", cropName)
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "cropName": cropName})
+}
+
+// DELETE /api/v1/crops/watchlist/:cropname
+func UnwatchCrop(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID, ok := getUserIDFromContext(r)
+	if !ok {
+		http.Error(w, "Invalid user", http.StatusBadRequest)
+		return
+	}
+
+	cropName := normalizeCropName(ps.ByName("cropname"))
+	res, err := db.CropWatchesCollection.DeleteOne(r.Context(), bson.M{"userId": userID, "cropName": cropName})
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to unwatch crop"})
+		return
+	}
+	if res.DeletedCount == 0 {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Crop not on watchlist"})
+		return
+	}
+	userdata.DelUserData("cropwatch", cropName, userID)
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+}
+
+func inStock(crop models.Crop) bool {
+	return crop.Quantity > 0 && !crop.OutOfStock
+}
+
+// notifyCropWatchers compares a crop listing before and after a change and
+// notifies watchers of that crop type about a restock or a price that has
+// dropped to their target. A nil before means the listing is new.
+func notifyCropWatchers(before *models.Crop, after models.Crop) {
+	if !inStock(after) {
+		return
+	}
+	restocked := before == nil || !inStock(*before)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cropName := normalizeCropName(after.Name)
+	cursor, err := db.CropWatchesCollection.Find(ctx, bson.M{"cropName": cropName})
+	if err != nil {
+		log.Printf("Crop watch lookup failed for %s: %v", cropName, err)
+		return
+	}
+	defer cursor.Close(ctx)
+
+	var watches []models.CropWatch
+	if err := cursor.All(ctx, &watches); err != nil {
+		log.Printf("Crop watch decode failed for %s: %v", cropName, err)
+		return
+	}
+
+	now := time.Now()
+	for _, watch := range watches {
+		if watch.LastNotifiedAt != nil && now.Sub(*watch.LastNotifiedAt) < watchNotifyCooldown {
+			continue
+		}
+		// A price alert fires when the listing crosses the target, not on every edit below it.
+		priceHit := watch.TargetPrice > 0 && after.Price <= watch.TargetPrice &&
+			(before == nil || before.Price > watch.TargetPrice || restocked)
+
+		switch {
+		case priceHit:
+			notifications.Notify(watch.UserID, "crop-price",
+				fmt.Sprintf("%s is now %.2f/%s", after.Name, after.Price, after.Unit),
+				fmt.Sprintf("A farm has listed %s at or below your target of %.2f.", after.Name, watch.TargetPrice),
+				"farm", after.FarmID.Hex())
+		case restocked:
+			notifications.Notify(watch.UserID, "crop-restock",
+				after.Name+" is back in stock",
+				fmt.Sprintf("%d %s available.", after.Quantity, after.Unit),
+				"farm", after.FarmID.Hex())
+		default:
+			continue
+		}
+
+		_, _ = db.CropWatchesCollection.UpdateOne(ctx, bson.M{"_id": watch.ID}, bson.M{"$set": bson.M{"lastNotifiedAt": now}})
+	}
+}
@@ -15,15 +15,20 @@ const (
 	AccessTokenTTL  = 15 * time.Minute   // 15 minutes
 )
 
-var (
-	// tokenSigningAlgo = jwt.SigningMethodHS256
-	JwtSecret = []byte("your_secret_key") // Replace with a secure secret key
-)
-
 type ContextKey string
 
 const UserIDKey ContextKey = "userId"
 
+// RolesKey holds the []string roles from the caller's access token.
+const RolesKey ContextKey = "roles"
+
+// SessionIDKey holds the session ID ("sid") from the caller's access token.
+const SessionIDKey ContextKey = "sessionId"
+
+// ScopesKey holds the []string scopes of the personal API token a request
+// was made with. It is unset for access tokens, which carry every scope.
+const ScopesKey ContextKey = "scopes"
+
 var CTX = context.Background()
 
 var RedisClient *redis.Client = rdx.Conn
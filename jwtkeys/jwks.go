@@ -0,0 +1,47 @@
+package jwtkeys
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// bigEndian is the minimal big-endian encoding of a small exponent.
+func bigEndian(n int) []byte {
+	var out []byte
+	for ; n > 0; n >>= 8 {
+		out = append([]byte{byte(n)}, out...)
+	}
+	return out
+}
+
+// GET /.well-known/jwks.json
+//
+// Publishes the public half of every asymmetric key in the keyring so other
+// services can verify our access tokens.
+func JWKS(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ring, err := keyring()
+	if err != nil {
+		http.Error(w, "Keyring unavailable", http.StatusInternalServerError)
+		return
+	}
+
+	keys := []map[string]string{}
+	for _, k := range ring.keys {
+		if jwk, ok := publicJWK(k); ok {
+			keys = append(keys, jwk)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["kid"] < keys[j]["kid"] })
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+}
@@ -0,0 +1,291 @@
+// Package jwtkeys holds the keyring access tokens are signed and verified
+// with. Keys are read once from configuration:
+//
+//	JWT_KEYS    path to a JSON keyring (below)
+//	JWT_SECRET  otherwise, a single HS256 secret with kid "default"
+//
+// One of them must be set; there is no built-in fallback. HS256 secrets
+// must be at least 32 bytes.
+//
+// The keyring names the active signing key and any number of others that
+// are still accepted:
+//
+//	{
+//	  "active": "2026-10",
+//	  "keys": [
+//	    {"kid": "2026-10", "alg": "EdDSA", "private_key": "keys/2026-10.pem"},
+//	    {"kid": "2026-04", "alg": "RS256", "public_key": "keys/2026-04.pub.pem"},
+//	    {"kid": "default", "alg": "HS256", "secret": "..."}
+//	  ]
+//	}
+//
+// Key paths are relative to the keyring file. To rotate, add the new key,
+// make it active and keep the old one (its public half is enough) until the
+// tokens it signed have expired. Every token carries the kid it was signed
+// with; tokens without one are checked against "default".
+//
+// Each key accepts only its own alg, so a token cannot pick a weaker
+// algorithm or pass a public key off as an HMAC secret. Asymmetric keys are
+// published by JWKS for other services.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DefaultKID is assumed for tokens that carry no kid.
+const DefaultKID = "default"
+
+// minSecretLen is the shortest HS256 secret accepted.
+const minSecretLen = 32
+
+// signingKey is one entry of the keyring. sign is nil for keys kept only to
+// verify tokens signed before a rotation.
+type signingKey struct {
+	ID     string
+	Method jwt.SigningMethod
+	sign   any
+	verify any
+}
+
+type ring struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+var (
+	once    sync.Once
+	current *ring
+	loadErr error
+)
+
+type fileKey struct {
+	KID        string `json:"kid"`
+	Alg        string `json:"alg"`
+	Secret     string `json:"secret"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+}
+
+type keyringFile struct {
+	Active string    `json:"active"`
+	Keys   []fileKey `json:"keys"`
+}
+
+func load() (*ring, error) {
+	if path := os.Getenv("JWT_KEYS"); path != "" {
+		return loadFile(path)
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("jwtkeys: neither JWT_KEYS nor JWT_SECRET is set")
+	}
+	if len(secret) < minSecretLen {
+		return nil, fmt.Errorf("jwtkeys: JWT_SECRET must be at least %d bytes", minSecretLen)
+	}
+	key := &signingKey{ID: DefaultKID, Method: jwt.SigningMethodHS256, sign: []byte(secret), verify: []byte(secret)}
+	return &ring{active: key, keys: map[string]*signingKey{DefaultKID: key}}, nil
+}
+
+func loadFile(path string) (*ring, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("jwtkeys: reading %s: %w", path, err)
+	}
+	var file keyringFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("jwtkeys: parsing %s: %w", path, err)
+	}
+
+	r := &ring{keys: map[string]*signingKey{}}
+	dir := filepath.Dir(path)
+	for _, fk := range file.Keys {
+		if fk.KID == "" {
+			return nil, errors.New("jwtkeys: every key needs a kid")
+		}
+		if _, dup := r.keys[fk.KID]; dup {
+			return nil, fmt.Errorf("jwtkeys: duplicate kid %q", fk.KID)
+		}
+		key, err := parseKey(fk, dir)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkeys: key %q: %w", fk.KID, err)
+		}
+		r.keys[fk.KID] = key
+	}
+
+	r.active = r.keys[file.Active]
+	if r.active == nil {
+		return nil, fmt.Errorf("jwtkeys: active key %q is not in the keyring", file.Active)
+	}
+	if r.active.sign == nil {
+		return nil, fmt.Errorf("jwtkeys: active key %q has no private key or secret", file.Active)
+	}
+	return r, nil
+}
+
+func readPEM(dir, path string) ([]byte, error) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return os.ReadFile(path)
+}
+
+func parseKey(fk fileKey, dir string) (*signingKey, error) {
+	key := &signingKey{ID: fk.KID}
+	switch fk.Alg {
+	case "HS256":
+		if len(fk.Secret) < minSecretLen {
+			return nil, fmt.Errorf("HS256 secret must be at least %d bytes", minSecretLen)
+		}
+		key.Method = jwt.SigningMethodHS256
+		key.sign, key.verify = []byte(fk.Secret), []byte(fk.Secret)
+		return key, nil
+
+	case "RS256":
+		key.Method = jwt.SigningMethodRS256
+		if fk.PrivateKey != "" {
+			pem, err := readPEM(dir, fk.PrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.sign, key.verify = priv, &priv.PublicKey
+			return key, nil
+		}
+		pem, err := readPEM(dir, fk.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := jwt.ParseRSAPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		key.verify = pub
+		return key, nil
+
+	case "EdDSA":
+		key.Method = jwt.SigningMethodEdDSA
+		if fk.PrivateKey != "" {
+			pem, err := readPEM(dir, fk.PrivateKey)
+			if err != nil {
+				return nil, err
+			}
+			priv, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+			if err != nil {
+				return nil, err
+			}
+			key.sign, key.verify = priv, priv.(crypto.Signer).Public()
+			return key, nil
+		}
+		pem, err := readPEM(dir, fk.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := jwt.ParseEdPublicKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		key.verify = pub
+		return key, nil
+	}
+	return nil, fmt.Errorf("unsupported alg %q (use HS256, RS256 or EdDSA)", fk.Alg)
+}
+
+func keyring() (*ring, error) {
+	once.Do(func() {
+		current, loadErr = load()
+		if loadErr != nil {
+			log.Printf("❌ %v", loadErr)
+		}
+	})
+	return current, loadErr
+}
+
+// Load reads the keyring now rather than on the first token, so a bad
+// configuration stops the server at startup.
+func Load() error {
+	_, err := keyring()
+	return err
+}
+
+// Sign signs claims with the active key and stamps its kid in the header.
+func Sign(claims jwt.Claims) (string, error) {
+	r, err := keyring()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(r.active.Method, claims)
+	token.Header["kid"] = r.active.ID
+	return token.SignedString(r.active.sign)
+}
+
+// keyFor finds the verification key named by the token's kid and checks
+// the token uses that key's algorithm.
+func keyFor(r *ring) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = DefaultKID
+		}
+		key, ok := r.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		if token.Method.Alg() != key.Method.Alg() {
+			return nil, fmt.Errorf("key %q does not accept alg %s", kid, token.Method.Alg())
+		}
+		return key.verify, nil
+	}
+}
+
+// Parse verifies tokenString (without the "Bearer " prefix) and decodes
+// it into claims.
+func Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	r, err := keyring()
+	if err != nil {
+		return nil, err
+	}
+	algs := make([]string, 0, 3)
+	seen := map[string]bool{}
+	for _, k := range r.keys {
+		if alg := k.Method.Alg(); !seen[alg] {
+			seen[alg] = true
+			algs = append(algs, alg)
+		}
+	}
+	return jwt.ParseWithClaims(tokenString, claims, keyFor(r), jwt.WithValidMethods(algs))
+}
+
+// publicJWK describes an asymmetric key in JWK form; HMAC keys are never
+// published.
+func publicJWK(k *signingKey) (map[string]string, bool) {
+	jwk := map[string]string{"kid": k.ID, "alg": k.Method.Alg(), "use": "sig"}
+	switch pub := k.verify.(type) {
+	case *rsa.PublicKey:
+		jwk["kty"] = "RSA"
+		jwk["n"] = b64(pub.N.Bytes())
+		jwk["e"] = b64(bigEndian(pub.E))
+	case ed25519.PublicKey:
+		jwk["kty"] = "OKP"
+		jwk["crv"] = "Ed25519"
+		jwk["x"] = b64(pub)
+	default:
+		return nil, false
+	}
+	return jwk, true
+}
@@ -0,0 +1,101 @@
+// Package mailer sends plain-text email. The backend is picked from the
+// environment on first use:
+//
+//	MAIL_DRIVER  smtp, log or none. Defaults to smtp when SMTP_HOST is set,
+//	             otherwise none.
+//	SMTP_HOST, SMTP_PORT (default 587), SMTP_USER, SMTP_PASS, MAIL_FROM
+//	MAIL_LOG_FILE  for the log driver, append messages to this file instead
+//	             of the server log.
+//
+// The log driver is meant for local development: nothing is delivered, but
+// every message (OTP codes included) can be read back.
+package mailer
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrNotConfigured is returned when no mail backend is set up.
+var ErrNotConfigured = errors.New("mailer: no mail driver configured")
+
+// Mailer delivers a single message.
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+var (
+	once    sync.Once
+	mu      sync.RWMutex
+	current Mailer
+)
+
+func fromEnv() Mailer {
+	driver := strings.ToLower(os.Getenv("MAIL_DRIVER"))
+	if driver == "" && os.Getenv("SMTP_HOST") != "" {
+		driver = "smtp"
+	}
+	switch driver {
+	case "smtp":
+		port := os.Getenv("SMTP_PORT")
+		if port == "" {
+			port = "587"
+		}
+		from := os.Getenv("MAIL_FROM")
+		if from == "" {
+			from = os.Getenv("SMTP_USER")
+		}
+		return &SMTP{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			User:     os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASS"),
+			From:     from,
+		}
+	case "log", "file":
+		return &Log{Path: os.Getenv("MAIL_LOG_FILE")}
+	case "", "none":
+		return nil
+	}
+	log.Printf("mailer: unknown MAIL_DRIVER %q, email disabled", driver)
+	return nil
+}
+
+func active() Mailer {
+	once.Do(func() {
+		m := fromEnv()
+		mu.Lock()
+		current = m
+		mu.Unlock()
+	})
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Use replaces the backend chosen from the environment.
+func Use(m Mailer) {
+	once.Do(func() {})
+	mu.Lock()
+	current = m
+	mu.Unlock()
+}
+
+// Configured reports whether email can be sent at all.
+func Configured() bool {
+	return active() != nil
+}
+
+// Send delivers a plain-text message to a single recipient.
+func Send(to, subject, body string) error {
+	m := active()
+	if m == nil {
+		return ErrNotConfigured
+	}
+	// Keep header values on one line so user data cannot add headers.
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+	return m.Send(to, subject, body)
+}
@@ -0,0 +1,56 @@
+package mailer
+
+import (
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"sync"
+	"time"
+)
+
+// SMTP sends through an SMTP server, authenticating when User is set.
+type SMTP struct {
+	Host     string
+	Port     string
+	User     string
+	Password string
+	From     string
+}
+
+func (s *SMTP) Send(to, subject, body string) error {
+	if s.Host == "" {
+		return ErrNotConfigured
+	}
+	msg := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		s.From, to, subject, body)
+
+	var auth smtp.Auth
+	if s.User != "" {
+		auth = smtp.PlainAuth("", s.User, s.Password, s.Host)
+	}
+	return smtp.SendMail(s.Host+":"+s.Port, auth, s.From, []string{to}, []byte(msg))
+}
+
+// Log writes messages to the server log, or appends them to Path if set.
+type Log struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (l *Log) Send(to, subject, body string) error {
+	if l.Path == "" {
+		log.Printf("mailer: to=%s subject=%q\n%s", to, subject, body)
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n----\n", time.Now().Format(time.RFC1123Z), to, subject, body)
+	return err
+}
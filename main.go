@@ -10,8 +10,17 @@ import (
 	"syscall"
 	"time"
 
+	"naevis/apitokens"
+	"naevis/db"
+	"naevis/farms"
+	"naevis/jwtkeys"
 	"naevis/newchat"
+	"naevis/oidc"
+	"naevis/privacy"
 	"naevis/ratelim"
+	"naevis/reactions"
+	"naevis/reports"
+	"naevis/reviews"
 	"naevis/routes"
 
 	"github.com/joho/godotenv"
@@ -59,13 +68,18 @@ func setupRouter(rateLimiter *ratelim.RateLimiter) *httprouter.Router {
 	router.GET("/health", Index)
 
 	routes.AddAdminRoutes(router)
+	routes.AddAPITokenRoutes(router)
 	routes.AddAuthRoutes(router)
+	routes.AddBlockRoutes(router)
 	routes.AddCartRoutes(router)
 	routes.AddCommentsRoutes(router)
 	routes.AddDiscordRoutes(router)
 	routes.RegisterFarmRoutes(router)
 	routes.AddHomeRoutes(router)
+	routes.AddNotificationRoutes(router)
+	routes.AddPrivacyRoutes(router)
 	routes.AddProfileRoutes(router)
+	routes.AddReactionRoutes(router)
 	routes.AddRecipeRoutes(router)
 	routes.AddReportRoutes(router)
 	routes.AddReviewsRoutes(router)
@@ -92,6 +106,25 @@ func main() {
 		port = ":" + port
 	}
 
+	// load JWT signing keys; refuse to start with a broken keyring
+	if err := jwtkeys.Load(); err != nil {
+		log.Fatal(err)
+	}
+	if err := oidc.Load(); err != nil {
+		log.Fatal(err)
+	}
+
+	// indexes the request path relies on
+	if err := apitokens.EnsureAPITokenIndexes(db.APITokensCollection); err != nil {
+		log.Fatal(err)
+	}
+	if err := reactions.EnsureReactionIndexes(db.ReactionsCollection); err != nil {
+		log.Fatal(err)
+	}
+	if err := reviews.EnsureReviewVoteIndexes(db.ReviewVotesCollection); err != nil {
+		log.Fatal(err)
+	}
+
 	// initialize rate limiter
 	rateLimiter := ratelim.NewRateLimiter()
 
@@ -99,6 +132,15 @@ func main() {
 	hub := newchat.NewHub()
 	go hub.Run()
 
+	// hard-delete soft-deleted farms once their restore window has passed
+	go farms.PurgeDeletedFarms()
+
+	// tell reporters how their resolved reports were handled
+	go reports.NotifyReporters()
+
+	// build data exports and carry out scheduled account erasures
+	go privacy.Run()
+
 	// build router and add chat routes with hub
 	router := setupRouter(rateLimiter)
 	routes.AddChatRoutes(router)         // existing chat routes without hub
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// APITokenPrefix starts every personal API token, telling them apart from
+// access tokens in the Authorization header.
+const APITokenPrefix = "fpat_"
+
+// Scopes a personal API token can be granted. A route accepts API tokens
+// only if it names the scope they need; see Authenticate.
+const (
+	ScopeFarmsRead   = "farms:read"
+	ScopeFarmsWrite  = "farms:write"
+	ScopeCropsWrite  = "crops:write"
+	ScopeOrdersRead  = "orders:read"
+	ScopeOrdersWrite = "orders:write"
+)
+
+// Scopes lists every valid scope.
+var Scopes = []string{ScopeFarmsRead, ScopeFarmsWrite, ScopeCropsWrite, ScopeOrdersRead, ScopeOrdersWrite}
+
+// IsScope reports whether scope is one of Scopes.
+func IsScope(scope string) bool {
+	for _, s := range Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// HashAPIToken is how API tokens are stored and looked up.
+func HashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// lastUsedEvery limits last-used writes to one per token per interval.
+const lastUsedEvery = time.Minute
+
+var errInvalidAPIToken = errors.New("invalid API token")
+
+// lookupAPIToken finds the live token raw stands for and records its use.
+func lookupAPIToken(ctx context.Context, raw string) (*models.APIToken, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return nil, errInvalidAPIToken
+	}
+	var token models.APIToken
+	if err := db.APITokensCollection.FindOne(ctx, bson.M{"hash": HashAPIToken(raw)}).Decode(&token); err != nil {
+		return nil, errInvalidAPIToken
+	}
+	now := time.Now()
+	if token.ExpiresAt != nil && !token.ExpiresAt.After(now) {
+		return nil, errInvalidAPIToken
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > lastUsedEvery {
+		db.APITokensCollection.UpdateOne(ctx,
+			bson.M{"_id": token.ID},
+			bson.M{"$set": bson.M{"lastUsedAt": now}},
+		)
+	}
+	return &token, nil
+}
+
+// hasScopes reports whether granted includes every one of needed.
+func hasScopes(granted, needed []string) bool {
+	for _, n := range needed {
+		found := false
+		for _, g := range granted {
+			if g == n {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
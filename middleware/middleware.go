@@ -2,24 +2,45 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"naevis/globals"
+	"naevis/jwtkeys"
+	"naevis/rdx"
 	"net/http"
+	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/gorilla/websocket"
 	"github.com/julienschmidt/httprouter"
 )
 
+// SuspensionKey is the Redis key marking a user as suspended. It expires
+// when the suspension ends, so Authenticate only has to check it exists.
+func SuspensionKey(userID string) string {
+	return "suspended:" + userID
+}
+
+// RevokedSessionKey is the Redis key marking a session as revoked. It
+// outlives the session's last access token, after which the refresh token
+// is what keeps the session out.
+func RevokedSessionKey(sessionID string) string {
+	return "revoked-session:" + sessionID
+}
+
 // JWT claims
 type Claims struct {
-	Username string   `json:"username"`
-	UserID   string   `json:"userId"`
-	Role     []string `json:"role"`
+	Username  string   `json:"username"`
+	UserID    string   `json:"userId"`
+	Role      []string `json:"role"`
+	SessionID string   `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func Authenticate(next httprouter.Handle) httprouter.Handle {
+// Authenticate lets through requests with a valid access token or personal
+// API token. API tokens are refused unless the route names scopes and the
+// token was granted all of them; access tokens carry every scope.
+func Authenticate(next httprouter.Handle, scopes ...string) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		if websocket.IsWebSocketUpgrade(r) {
 			// Allow WebSocket through without setting body/headers yet
@@ -38,33 +59,77 @@ func Authenticate(next httprouter.Handle) httprouter.Handle {
 			return
 		}
 
+		if strings.HasPrefix(tokenString[7:], APITokenPrefix) {
+			authenticateAPIToken(w, r, ps, next, tokenString[7:], scopes)
+			return
+		}
+
 		claims := &Claims{}
-		token, err := jwt.ParseWithClaims(tokenString[7:], claims, func(token *jwt.Token) (any, error) {
-			return globals.JwtSecret, nil
-		})
+		token, err := jwtkeys.Parse(tokenString[7:], claims)
 		if err != nil || !token.Valid {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
+		switch CheckAccess(claims) {
+		case nil:
+		case errSuspended:
+			http.Error(w, "Account suspended", http.StatusForbidden)
+			return
+		default:
+			http.Error(w, "Session revoked", http.StatusUnauthorized)
+			return
+		}
 
-		// Store UserID in context
+		// Store UserID, roles and session in context
 		ctx := context.WithValue(r.Context(), globals.UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, globals.RolesKey, claims.Role)
+		ctx = context.WithValue(ctx, globals.SessionIDKey, claims.SessionID)
 		// Pass updated context to the next handler
 		next(w, r.WithContext(ctx), ps)
 	}
 }
 
+func authenticateAPIToken(w http.ResponseWriter, r *http.Request, ps httprouter.Params, next httprouter.Handle, raw string, scopes []string) {
+	if len(scopes) == 0 {
+		http.Error(w, "API tokens are not accepted here", http.StatusForbidden)
+		return
+	}
+	token, err := lookupAPIToken(r.Context(), raw)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+	if !hasScopes(token.Scopes, scopes) {
+		http.Error(w, "Token is missing scope "+strings.Join(scopes, ", "), http.StatusForbidden)
+		return
+	}
+	if rdx.Exists(SuspensionKey(token.UserID)) {
+		http.Error(w, "Account suspended", http.StatusForbidden)
+		return
+	}
+	// API tokens outlive role changes, so roles come from the user record
+	roles, err := currentRoles(r.Context(), token.UserID)
+	if err != nil {
+		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), globals.UserIDKey, token.UserID)
+	ctx = context.WithValue(ctx, globals.RolesKey, roles)
+	ctx = context.WithValue(ctx, globals.ScopesKey, token.Scopes)
+	next(w, r.WithContext(ctx), ps)
+}
+
 func OptionalAuth(next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		tokenString := r.Header.Get("Authorization")
 		if len(tokenString) >= 8 && tokenString[:7] == "Bearer " {
 			claims := &Claims{}
-			token, err := jwt.ParseWithClaims(tokenString[7:], claims, func(token *jwt.Token) (any, error) {
-				return globals.JwtSecret, nil
-			})
-			if err == nil && token.Valid {
-				// Add user ID to context if token is valid
-				r = r.WithContext(context.WithValue(r.Context(), globals.UserIDKey, claims.UserID))
+			token, err := jwtkeys.Parse(tokenString[7:], claims)
+			if err == nil && token.Valid && CheckAccess(claims) == nil {
+				// Add user ID and roles to context if token is valid
+				ctx := context.WithValue(r.Context(), globals.UserIDKey, claims.UserID)
+				r = r.WithContext(context.WithValue(ctx, globals.RolesKey, claims.Role))
 			}
 		}
 		// Proceed regardless of token state
@@ -72,18 +137,53 @@ func OptionalAuth(next httprouter.Handle) httprouter.Handle {
 	}
 }
 
+// HasRole reports whether the authenticated caller holds any of roles.
+func HasRole(ctx context.Context, roles ...string) bool {
+	held, _ := ctx.Value(globals.RolesKey).([]string)
+	for _, h := range held {
+		for _, role := range roles {
+			if h == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var (
+	errSessionRevoked = errors.New("session revoked")
+	errSuspended      = errors.New("account suspended")
+)
+
+// CheckAccess applies the checks a validly signed access token still has
+// to pass: its session must not be revoked nor its user suspended. Every
+// way of accepting an access token goes through it, and long-lived
+// connections repeat it as they go.
+func CheckAccess(claims *Claims) error {
+	if claims.SessionID != "" && rdx.Exists(RevokedSessionKey(claims.SessionID)) {
+		return errSessionRevoked
+	}
+	if rdx.Exists(SuspensionKey(claims.UserID)) {
+		return errSuspended
+	}
+	return nil
+}
+
+// ValidateJWT checks a "Bearer ..." header value the way Authenticate does,
+// for handlers that read the token themselves.
 func ValidateJWT(tokenString string) (*Claims, error) {
 	if tokenString == "" || len(tokenString) < 8 {
 		return nil, fmt.Errorf("invalid token")
 	}
 
 	claims := &Claims{}
-	_, err := jwt.ParseWithClaims(tokenString[7:], claims, func(token *jwt.Token) (any, error) {
-		return globals.JwtSecret, nil
-	})
+	_, err := jwtkeys.Parse(tokenString[7:], claims)
 	if err != nil {
 		return nil, fmt.Errorf("unauthorized: %w", err)
 	}
+	if err := CheckAccess(claims); err != nil {
+		return nil, fmt.Errorf("unauthorized: %w", err)
+	}
 	return claims, nil
 }
 
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"naevis/db"
+	"naevis/globals"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Roles a user can hold, stored in the user's "role" field. Everyone signs
+// up as a buyer, creating a farm makes a user a farmer, moderators work the
+// report queue, and admins pass every role check.
+const (
+	RoleAdmin     = "admin"
+	RoleModerator = "moderator"
+	RoleFarmer    = "farmer"
+	RoleBuyer     = "buyer"
+)
+
+// Roles lists every valid role.
+var Roles = []string{RoleAdmin, RoleModerator, RoleFarmer, RoleBuyer}
+
+// IsRole reports whether role is one of Roles.
+func IsRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// RequireRole lets a request through only if the caller holds one of roles
+// or is an admin. It must be wrapped by Authenticate. Roles are read from
+// the user record rather than the token, so granting or revoking a role
+// takes effect on the next request instead of the next login.
+func RequireRole(roles ...string) func(httprouter.Handle) httprouter.Handle {
+	allowed := append([]string{RoleAdmin}, roles...)
+	return func(next httprouter.Handle) httprouter.Handle {
+		return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+			userID, _ := r.Context().Value(globals.UserIDKey).(string)
+			if userID == "" {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			held, err := currentRoles(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), globals.RolesKey, held)
+			if !HasRole(ctx, allowed...) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next(w, r.WithContext(ctx), ps)
+		}
+	}
+}
+
+// IsModerator reports whether userID currently holds the moderator or admin
+// role. Like RequireRole it reads the user record, not the token, for
+// handlers that only need the role to decide about other users' content.
+func IsModerator(ctx context.Context, userID string) bool {
+	if userID == "" {
+		return false
+	}
+	held, err := currentRoles(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return HasRole(context.WithValue(ctx, globals.RolesKey, held), RoleAdmin, RoleModerator)
+}
+
+func currentRoles(ctx context.Context, userID string) ([]string, error) {
+	var user struct {
+		Role []string `bson:"role"`
+	}
+	err := db.UserCollection.FindOne(ctx, bson.M{"userid": userID},
+		options.FindOne().SetProjection(bson.M{"role": 1})).Decode(&user)
+	return user.Role, err
+}
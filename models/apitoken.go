@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIToken is a personal access token a user creates for scripts and
+// integrations. Only the SHA-256 of the token is kept; Prefix is its first
+// characters, so the user can tell tokens apart.
+type APIToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	UserID     string             `bson:"userid"               json:"-"`
+	Name       string             `bson:"name"                 json:"name"`
+	Prefix     string             `bson:"prefix"               json:"prefix"`
+	Hash       string             `bson:"hash"                 json:"-"`
+	Scopes     []string           `bson:"scopes"               json:"scopes"`
+	CreatedAt  time.Time          `bson:"createdAt"            json:"createdAt"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty"  json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time         `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Block records that BlockerID has blocked BlockedID. Blocking is one-way
+// in the data but cuts contact both ways: neither side can chat with or
+// follow the other.
+type Block struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	BlockerID string             `bson:"blocker"       json:"blocker"`
+	BlockedID string             `bson:"blocked"       json:"blocked"`
+	CreatedAt time.Time          `bson:"createdAt"     json:"createdAt"`
+}
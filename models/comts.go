@@ -6,8 +6,13 @@ type Comment struct {
 	ID         string    `json:"_id" bson:"_id,omitempty"`
 	EntityType string    `json:"entityType" bson:"entity_type"`
 	EntityID   string    `json:"entityId" bson:"entity_id"`
+	ParentID   string    `json:"parentId,omitempty" bson:"parent_id,omitempty"` // empty for top-level comments
+	Depth      int       `json:"depth" bson:"depth"`                            // 0 for top-level comments
 	Content    string    `json:"content" bson:"content"`
+	Mentions   []string  `json:"mentions,omitempty" bson:"mentions,omitempty"` // user IDs of @mentioned users
+	ReplyCount int       `json:"replyCount" bson:"reply_count"`
 	CreatedBy  string    `json:"created_by" bson:"created_by"`
 	CreatedAt  time.Time `json:"createdAt" bson:"created_at"`
 	UpdatedAt  time.Time `json:"updatedAt" bson:"updated_at"`
+	Hidden     bool      `json:"hidden,omitempty" bson:"hidden,omitempty"` // held by the content filter or hidden by a moderator
 }
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DataExport is a user's request for a copy of their data. It is built in
+// the background; once Status is "ready" the ZIP can be downloaded until
+// ExpiresAt.
+type DataExport struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"       json:"id"`
+	UserID    string             `bson:"userid"              json:"-"`
+	Status    string             `bson:"status"              json:"status"` // pending, running, ready, failed, expired
+	File      string             `bson:"file,omitempty"      json:"-"`
+	Size      int64              `bson:"size,omitempty"      json:"size,omitempty"`
+	Error     string             `bson:"error,omitempty"     json:"error,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt"           json:"createdAt"`
+	StartedAt *time.Time         `bson:"startedAt,omitempty" json:"-"`
+	ReadyAt   *time.Time         `bson:"readyAt,omitempty"   json:"readyAt,omitempty"`
+	ExpiresAt *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+}
@@ -39,12 +39,48 @@ type Farm struct {
 	AvgRating          float64     `bson:"avgRating,omitempty"   json:"avgRating,omitempty"`
 	ReviewCount        int         `bson:"reviewCount,omitempty" json:"reviewCount,omitempty"`
 	FavoritesCount     int64       `bson:"favoritesCount,omitempty" json:"favoritesCount,omitempty"`
+	Verified           bool        `bson:"verified,omitempty"    json:"verified,omitempty"`
+	VerifiedBuyersOnly bool        `bson:"verifiedBuyersOnly,omitempty" json:"verifiedBuyersOnly,omitempty"` // only buyers with a delivered order may review
+	VerifiedAt         *time.Time  `bson:"verifiedAt,omitempty"  json:"verifiedAt,omitempty"`
+	Badges             []FarmBadge `bson:"badges,omitempty"      json:"badges,omitempty"`
 	CreatedBy          string      `bson:"createdBy"             json:"createdBy"`
 	CreatedAt          time.Time   `bson:"createdAt"             json:"createdAt"`
 	UpdatedAt          time.Time   `bson:"updatedAt"             json:"updatedAt"`
+	DeletedAt          *time.Time  `bson:"deletedAt,omitempty"   json:"deletedAt,omitempty"`
+	ModerationHold     string      `bson:"moderationHold,omitempty" json:"moderationHold,omitempty"` // "hide" or "delete" when taken down by a moderator
 	Contact            string      `json:"contact"`
 }
 
+// FarmBadge is a certification granted by an admin, e.g. "organic".
+// A nil ExpiresAt means the badge does not lapse.
+type FarmBadge struct {
+	Type      string     `bson:"type"                json:"type"`
+	GrantedAt time.Time  `bson:"grantedAt"           json:"grantedAt"`
+	ExpiresAt *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+}
+
+// ValidFarmBadges lists the certification badges an admin may grant.
+var ValidFarmBadges = map[string]bool{
+	"organic":        true,
+	"pesticide-free": true,
+}
+
+// FarmVerification is an owner's request to have a farm verified, along with
+// the supporting documents and the admin's decision.
+type FarmVerification struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty"         json:"id"`
+	FarmID      primitive.ObjectID `bson:"farmId"                json:"farmId"`
+	SubmittedBy string             `bson:"submittedBy"           json:"submittedBy"`
+	Documents   []string           `bson:"documents"             json:"documents"`
+	Badges      []string           `bson:"badges,omitempty"      json:"badges,omitempty"` // badges the owner is applying for
+	Notes       string             `bson:"notes,omitempty"       json:"notes,omitempty"`
+	Status      string             `bson:"status"                json:"status"` // pending, approved, rejected
+	ReviewedBy  string             `bson:"reviewedBy,omitempty"  json:"reviewedBy,omitempty"`
+	ReviewNotes string             `bson:"reviewNotes,omitempty" json:"reviewNotes,omitempty"`
+	CreatedAt   time.Time          `bson:"createdAt"             json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt"             json:"updatedAt"`
+}
+
 // type Farm struct {
 // 	ID                 primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 // 	Name               string             `json:"name"`
@@ -84,7 +120,22 @@ type Crop struct {
 	PriceHistory []PricePoint       `json:"priceHistory,omitempty"`
 	FieldPlot    string             `json:"fieldPlot,omitempty"`
 	CreatedAt    time.Time          `json:"createdAt"`
+	DeletedAt    *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
 	FarmID       primitive.ObjectID `bson:"farmId,omitempty" json:"farmId,omitempty"`
+	AvgRating    float64            `bson:"avgRating,omitempty" json:"avgRating,omitempty"`
+	ReviewCount  int                `bson:"reviewCount,omitempty" json:"reviewCount,omitempty"`
+}
+
+// CropWatch is a user's subscription to a crop type. Watchers are notified
+// when the crop comes back in stock or, if TargetPrice is set, when a farm
+// lists it at or below that price.
+type CropWatch struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty"            json:"id"`
+	UserID         string             `bson:"userId"                   json:"userId"`
+	CropName       string             `bson:"cropName"                 json:"cropName"` // lowercased crop name
+	TargetPrice    float64            `bson:"targetPrice,omitempty"    json:"targetPrice,omitempty"`
+	CreatedAt      time.Time          `bson:"createdAt"                json:"createdAt"`
+	LastNotifiedAt *time.Time         `bson:"lastNotifiedAt,omitempty" json:"lastNotifiedAt,omitempty"`
 }
 
 type FarmOrder struct {
@@ -94,6 +145,7 @@ type FarmOrder struct {
 	CropID          primitive.ObjectID `bson:"cropId"         json:"cropId"`
 	Quantity        int                `bson:"quantity"       json:"quantity"`
 	PriceAtPurchase float64            `bson:"priceAtPurchase" json:"priceAtPurchase"`
+	Status          string             `bson:"status,omitempty" json:"status,omitempty"`
 	BoughtAt        time.Time          `bson:"boughtAt"       json:"boughtAt"`
 }
 
@@ -116,6 +168,9 @@ type CropListing struct {
 	AvailableQtyKg int      `json:"availableQtyKg,omitempty"`
 	HarvestDate    string   `json:"harvestDate,omitempty"` // ISO string
 	Tags           []string `json:"tags,omitempty"`
+	Verified       bool     `json:"verified,omitempty"`
+	AvgRating      float64  `json:"avgRating,omitempty"`
+	ReviewCount    int      `json:"reviewCount,omitempty"`
 }
 
 // //	type Product struct {
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ModerationAction records what a moderator did about a reported target. It
+// is stored on every report about that target and can be reverted.
+type ModerationAction struct {
+	ID          primitive.ObjectID `json:"id"                    bson:"id"`
+	Action      string             `json:"action"                bson:"action"` // hide, delete, warn, suspend
+	TargetType  string             `json:"targetType"            bson:"targetType"`
+	TargetID    string             `json:"targetId"              bson:"targetId"`
+	UserID      string             `json:"userId,omitempty"      bson:"userId,omitempty"` // author of the content, or the user acted on
+	ModeratorID string             `json:"moderatorId"           bson:"moderatorId"`
+	Notes       string             `json:"notes,omitempty"       bson:"notes,omitempty"`
+	Until       *time.Time         `json:"until,omitempty"       bson:"until,omitempty"`     // suspensions only
+	PrevUntil   *time.Time         `json:"-"                     bson:"prevUntil,omitempty"` // suspension in force before this one
+	Snapshot    bson.Raw           `json:"-"                     bson:"snapshot,omitempty"`  // deleted document, for restoring
+	CreatedAt   time.Time          `json:"createdAt"             bson:"createdAt"`
+	RevertedAt  *time.Time         `json:"revertedAt,omitempty"  bson:"revertedAt,omitempty"`
+	RevertedBy  string             `json:"revertedBy,omitempty"  bson:"revertedBy,omitempty"`
+}
+
+// ReportTarget is one reported item with its reports rolled up, as listed
+// in the moderation queue.
+type ReportTarget struct {
+	TargetType      string    `json:"targetType"     bson:"targetType"`
+	TargetID        string    `json:"targetId"       bson:"targetId"`
+	Count           int       `json:"count"          bson:"count"`
+	Reasons         []string  `json:"reasons"        bson:"reasons"`
+	ReportIDs       []string  `json:"reportIds"      bson:"reportIds"`
+	FirstReportedAt time.Time `json:"firstReportedAt" bson:"firstReportedAt"`
+	LastReportedAt  time.Time `json:"lastReportedAt" bson:"lastReportedAt"`
+}
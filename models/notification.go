@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification is an in-app message shown to a single user.
+type Notification struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty"        json:"id"`
+	UserID     string             `bson:"userId"               json:"userId"`
+	Type       string             `bson:"type"                 json:"type"` // e.g. "crop-restock", "crop-price"
+	Title      string             `bson:"title"                json:"title"`
+	Body       string             `bson:"body,omitempty"       json:"body,omitempty"`
+	EntityType string             `bson:"entityType,omitempty" json:"entityType,omitempty"`
+	EntityID   string             `bson:"entityId,omitempty"   json:"entityId,omitempty"`
+	Read       bool               `bson:"read"                 json:"read"`
+	CreatedAt  time.Time          `bson:"createdAt"            json:"createdAt"`
+}
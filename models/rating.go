@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// RatingSummary is the running review aggregate for one entity. Histogram is
+// keyed by star value ("1".."5") so individual buckets can be $inc'd.
+type RatingSummary struct {
+	EntityType string         `bson:"entity_type" json:"entityType"`
+	EntityID   string         `bson:"entity_id"   json:"entityId"`
+	Count      int            `bson:"count"       json:"count"`
+	Sum        int            `bson:"sum"         json:"-"`
+	Average    float64        `bson:"average"     json:"average"`
+	Histogram  map[string]int `bson:"histogram"   json:"histogram"`
+	UpdatedAt  time.Time      `bson:"updatedAt"   json:"updatedAt"`
+}
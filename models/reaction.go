@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reaction is one user's reaction (e.g. "like") on an entity. A user may
+// leave several different reactions on the same entity, but each only once.
+type Reaction struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	EntityType string             `bson:"entity_type"   json:"entityType"`
+	EntityID   string             `bson:"entity_id"     json:"entityId"`
+	UserID     string             `bson:"userid"        json:"userid"`
+	Reaction   string             `bson:"reaction"      json:"reaction"`
+	CreatedAt  time.Time          `bson:"createdAt"     json:"createdAt"`
+}
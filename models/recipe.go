@@ -31,4 +31,7 @@ type Recipe struct {
 	Servings    int                `json:"servings" bson:"servings"`
 	CreatedAt   int64              `json:"createdAt" bson:"createdAt"`
 	Views       int                `json:"views" bson:"views"`
+	AvgRating   float64            `json:"avgRating,omitempty" bson:"avgRating,omitempty"`
+	ReviewCount int                `json:"reviewCount,omitempty" bson:"reviewCount,omitempty"`
+	Hidden      bool               `json:"hidden,omitempty" bson:"hidden,omitempty"` // held by the content filter or hidden by a moderator
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RefreshToken is one issued refresh token, stored by hash. Each login
+// starts a family; every refresh rotates the token within it. A rotated-out
+// token that comes back means the family leaked, so all of it is revoked.
+type RefreshToken struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    string             `bson:"userid"`
+	Hash      string             `bson:"hash"`
+	FamilyID  string             `bson:"familyId"`
+	DeviceID  string             `bson:"deviceId"`
+	CreatedAt time.Time          `bson:"createdAt"`
+	ExpiresAt time.Time          `bson:"expiresAt"`
+	RotatedAt *time.Time         `bson:"rotatedAt,omitempty"`
+	RevokedAt *time.Time         `bson:"revokedAt,omitempty"`
+}
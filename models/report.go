@@ -7,6 +7,10 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// SystemReporter is the reportedBy of reports filed automatically by the
+// content filter.
+const SystemReporter = "system"
+
 type Report struct {
 	// We store the Mongo-generated ObjectID here.  In JSON, we’ll expose it as a hex string “id”.
 	ID primitive.ObjectID `bson:"_id,omitempty"`
@@ -28,6 +32,15 @@ type Report struct {
 
 	// New field to indicate whether the reporter has been notified
 	Notified bool `json:"notified" bson:"notified"`
+
+	// Delivery state for the reporter notifier; cleared whenever the report
+	// is resolved again
+	NotifiedInApp  bool       `json:"-" bson:"notifiedInApp,omitempty"`
+	NotifyAttempts int        `json:"-" bson:"notifyAttempts,omitempty"`
+	NextNotifyAt   *time.Time `json:"-" bson:"nextNotifyAt,omitempty"`
+
+	// Moderation actions taken on the target, oldest first
+	Actions []ModerationAction `json:"actions,omitempty" bson:"actions,omitempty"`
 }
 
 // MarshalJSON implements a custom JSON marshaller so that “id” is the hex string of ObjectID.
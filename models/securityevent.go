@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SecurityEvent is one entry of the security log: a login attempt and how
+// it went. UserID is empty when the username matched no account.
+type SecurityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"       json:"id"`
+	UserID    string             `bson:"userid,omitempty"    json:"-"`
+	Username  string             `bson:"username"            json:"username"`
+	Type      string             `bson:"type"                json:"type"` // "login"
+	Success   bool               `bson:"success"             json:"success"`
+	Reason    string             `bson:"reason,omitempty"    json:"reason,omitempty"` // why it failed, e.g. "bad-password", "locked"
+	IP        string             `bson:"ip"                  json:"ip"`
+	UserAgent string             `bson:"userAgent"           json:"userAgent"`
+	DeviceID  string             `bson:"deviceId,omitempty"  json:"deviceId,omitempty"`
+	Country   string             `bson:"country,omitempty"   json:"country,omitempty"`
+	CreatedAt time.Time          `bson:"createdAt"           json:"createdAt"`
+}
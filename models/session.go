@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Session is one login on one device. Its ID is carried in access tokens
+// as "sid" and doubles as the family ID of its refresh tokens.
+type Session struct {
+	ID         string     `bson:"_id"                 json:"id"`
+	UserID     string     `bson:"userid"              json:"-"`
+	DeviceID   string     `bson:"deviceId"            json:"deviceId"`
+	IP         string     `bson:"ip"                  json:"ip"`
+	UserAgent  string     `bson:"userAgent"           json:"userAgent"`
+	CreatedAt  time.Time  `bson:"createdAt"           json:"createdAt"`
+	LastSeenAt time.Time  `bson:"lastSeenAt"          json:"lastSeenAt"`
+	RevokedAt  *time.Time `bson:"revokedAt,omitempty" json:"revokedAt,omitempty"`
+	Current    bool       `bson:"-"                   json:"current"`
+}
@@ -68,6 +68,7 @@ type Message struct {
 	FileType  string             `bson:"fileType,omitempty" json:"fileType,omitempty"` // "image" or "video"
 	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 	ReplyTo   *ReplyRef          `bson:"replyTo,omitempty" json:"replyTo,omitempty"`
+	Hidden    bool               `bson:"hidden,omitempty" json:"hidden,omitempty"` // held by the content filter
 }
 
 type Chat struct {
@@ -0,0 +1,405 @@
+// Package moderation applies moderators' decisions on reported content and
+// users. Actions are addressed by the reports' targetType/targetId, recorded
+// on every report about the target, and can be reverted.
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/middleware"
+	"naevis/models"
+	"naevis/notifications"
+	"naevis/rdx"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultSuspensionDays = 7
+	maxSuspensionDays     = 365
+)
+
+var closedReportStatuses = []string{"resolved", "rejected"}
+
+// ApplyAction takes a moderation action on a reported target.
+//
+// Endpoint: POST /api/v1/admin/report-targets/:targettype/:targetid/actions
+//
+// Body: { "action": "hide"|"delete"|"warn"|"suspend", "notes": "...", "days": 7 }
+//
+// hide and delete apply to the content itself; warn and suspend apply to
+// its author, or to the user when targetType is "user". Open reports on the
+// target are marked resolved.
+func ApplyAction(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	targetType, targetID := ps.ByName("targettype"), ps.ByName("targetid")
+
+	var payload struct {
+		Action string `json:"action"`
+		Notes  string `json:"notes"`
+		Days   int    `json:"days"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, `{"error":"Invalid JSON payload"}`, http.StatusBadRequest)
+		return
+	}
+
+	action := models.ModerationAction{
+		ID:          primitive.NewObjectID(),
+		Action:      strings.TrimSpace(payload.Action),
+		TargetType:  targetType,
+		TargetID:    targetID,
+		ModeratorID: utils.GetUserIDFromRequest(r),
+		Notes:       strings.TrimSpace(payload.Notes),
+		CreatedAt:   time.Now().UTC().Truncate(time.Millisecond),
+	}
+
+	ctx := r.Context()
+	reported, err := db.ReportsCollection.CountDocuments(ctx, bson.M{"targetType": targetType, "targetId": targetID})
+	if err != nil {
+		http.Error(w, `{"error":"Failed to look up reports"}`, http.StatusInternalServerError)
+		return
+	}
+	if reported == 0 {
+		http.Error(w, `{"error":"No reports for this target"}`, http.StatusNotFound)
+		return
+	}
+
+	status, msg := apply(ctx, &action, payload.Days)
+	if status != http.StatusOK {
+		http.Error(w, `{"error":"`+msg+`"}`, status)
+		return
+	}
+
+	if err := recordAction(ctx, action); err != nil {
+		http.Error(w, `{"error":"Action applied but not recorded on reports"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(action)
+}
+
+// RevertAction undoes an earlier moderation action.
+//
+// Endpoint: DELETE /api/v1/admin/report-targets/:targettype/:targetid/actions/:actionid
+func RevertAction(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	actionID, err := primitive.ObjectIDFromHex(ps.ByName("actionid"))
+	if err != nil {
+		http.Error(w, `{"error":"Invalid action ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	action, err := findAction(ctx, ps.ByName("targettype"), ps.ByName("targetid"), actionID)
+	if err == mongo.ErrNoDocuments {
+		http.Error(w, `{"error":"Action not found"}`, http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, `{"error":"Failed to look up action"}`, http.StatusInternalServerError)
+		return
+	}
+	if action.RevertedAt != nil {
+		http.Error(w, `{"error":"Action already reverted"}`, http.StatusConflict)
+		return
+	}
+
+	if status, msg := revert(ctx, action); status != http.StatusOK {
+		http.Error(w, `{"error":"`+msg+`"}`, status)
+		return
+	}
+
+	now := time.Now().UTC()
+	moderator := utils.GetUserIDFromRequest(r)
+	_, err = db.ReportsCollection.UpdateMany(ctx,
+		bson.M{"actions.id": actionID},
+		bson.M{"$set": bson.M{
+			"actions.$[a].revertedAt": now,
+			"actions.$[a].revertedBy": moderator,
+			"updatedAt":               now,
+		}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []any{bson.M{"a.id": actionID}}}),
+	)
+	if err != nil {
+		http.Error(w, `{"error":"Action reverted but not recorded on reports"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Action reverted"})
+}
+
+// apply carries out the action and fills in the affected user. It returns
+// an HTTP status and, on failure, a message for the client.
+func apply(ctx context.Context, action *models.ModerationAction, days int) (int, string) {
+	switch action.Action {
+	case "hide", "delete":
+		t, err := resolveTarget(action.TargetType, action.TargetID)
+		if err != nil {
+			return http.StatusBadRequest, "Content of this type cannot be hidden or deleted"
+		}
+		active, err := activeTakeDowns(ctx, action.TargetType, action.TargetID)
+		if err != nil {
+			return http.StatusInternalServerError, "Failed to look up earlier actions"
+		}
+		if !canStack(*action, active) {
+			return http.StatusConflict, "Target is already taken down; revert that action first"
+		}
+		doc, err := t.load(ctx)
+		if err != nil {
+			return http.StatusNotFound, "Target not found"
+		}
+		action.UserID = t.author(doc)
+		if err := takeDown(ctx, action, t, doc); err != nil {
+			return http.StatusInternalServerError, "Failed to take down target"
+		}
+		if action.UserID != "" {
+			go notifications.Notify(action.UserID, "moderation-removal", "Your "+action.TargetType+" was removed by a moderator", action.Notes, action.TargetType, action.TargetID)
+		}
+
+	case "warn", "suspend":
+		userID, err := targetUser(ctx, action.TargetType, action.TargetID)
+		if err != nil || userID == "" {
+			return http.StatusNotFound, "Could not find a user to act on"
+		}
+		action.UserID = userID
+		if action.Action == "warn" {
+			return warn(ctx, *action)
+		}
+		return suspend(ctx, action, days)
+
+	default:
+		return http.StatusBadRequest, "Action must be hide, delete, warn or suspend"
+	}
+	return http.StatusOK, ""
+}
+
+func revert(ctx context.Context, action models.ModerationAction) (int, string) {
+	switch action.Action {
+	case "hide", "delete":
+		t, err := resolveTarget(action.TargetType, action.TargetID)
+		if err != nil {
+			return http.StatusBadRequest, "Unsupported target type"
+		}
+		if action.Action == "hide" {
+			active, err := activeTakeDowns(ctx, action.TargetType, action.TargetID)
+			if err != nil {
+				return http.StatusInternalServerError, "Failed to look up earlier actions"
+			}
+			for _, a := range active {
+				if a.Action == "delete" {
+					return http.StatusConflict, "Target was deleted after being hidden; revert the delete first"
+				}
+			}
+		}
+		if err := restore(ctx, action, t); err != nil {
+			return http.StatusInternalServerError, "Failed to restore target"
+		}
+	case "warn":
+		_, err := db.UserCollection.UpdateOne(ctx,
+			bson.M{"userid": action.UserID, "warnings": bson.M{"$gt": 0}},
+			bson.M{"$inc": bson.M{"warnings": -1}},
+		)
+		if err != nil {
+			return http.StatusInternalServerError, "Failed to withdraw warning"
+		}
+	case "suspend":
+		if err := setSuspension(ctx, action.UserID, action.PrevUntil); err != nil {
+			return http.StatusInternalServerError, "Failed to lift suspension"
+		}
+		go notifications.Notify(action.UserID, "moderation-unsuspended", "Your suspension was lifted", "", "user", action.UserID)
+	}
+	return http.StatusOK, ""
+}
+
+func warn(ctx context.Context, action models.ModerationAction) (int, string) {
+	_, err := db.UserCollection.UpdateOne(ctx, bson.M{"userid": action.UserID}, bson.M{"$inc": bson.M{"warnings": 1}})
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to warn user"
+	}
+	go notifications.Notify(action.UserID, "moderation-warning", "You received a warning from a moderator", action.Notes, action.TargetType, action.TargetID)
+	return http.StatusOK, ""
+}
+
+// suspend blocks the user for the given number of days. Any suspension
+// already in force is remembered so reverting this one puts it back.
+func suspend(ctx context.Context, action *models.ModerationAction, days int) (int, string) {
+	if days == 0 {
+		days = defaultSuspensionDays
+	}
+	if days < 0 || days > maxSuspensionDays {
+		return http.StatusBadRequest, "Suspension must be between 1 and 365 days"
+	}
+
+	var user struct {
+		SuspendedUntil *time.Time `bson:"suspended_until"`
+	}
+	if err := db.UserCollection.FindOne(ctx, bson.M{"userid": action.UserID}).Decode(&user); err != nil {
+		return http.StatusNotFound, "User not found"
+	}
+	if user.SuspendedUntil != nil && user.SuspendedUntil.After(time.Now()) {
+		action.PrevUntil = user.SuspendedUntil
+	}
+
+	until := action.CreatedAt.AddDate(0, 0, days)
+	action.Until = &until
+	if err := setSuspension(ctx, action.UserID, action.Until); err != nil {
+		return http.StatusInternalServerError, "Failed to suspend user"
+	}
+	go notifications.Notify(action.UserID, "moderation-suspension", "Your account is suspended until "+until.Format("2 Jan 2006"), action.Notes, action.TargetType, action.TargetID)
+	return http.StatusOK, ""
+}
+
+// setSuspension stores the suspension end on the user and mirrors it into
+// Redis for middleware.Authenticate. A nil or past time lifts it.
+func setSuspension(ctx context.Context, userID string, until *time.Time) error {
+	if until == nil || !until.After(time.Now()) {
+		if _, err := db.UserCollection.UpdateOne(ctx, bson.M{"userid": userID}, bson.M{"$unset": bson.M{"suspended_until": ""}}); err != nil {
+			return err
+		}
+		_, err := rdx.RdxDel(middleware.SuspensionKey(userID))
+		return err
+	}
+
+	if _, err := db.UserCollection.UpdateOne(ctx, bson.M{"userid": userID}, bson.M{"$set": bson.M{"suspended_until": *until}}); err != nil {
+		return err
+	}
+	return rdx.SetWithExpiry(middleware.SuspensionKey(userID), until.Format(time.RFC3339), time.Until(*until))
+}
+
+// targetUser returns the user a warning or suspension applies to.
+func targetUser(ctx context.Context, targetType, targetID string) (string, error) {
+	if targetType == "user" {
+		n, err := db.UserCollection.CountDocuments(ctx, bson.M{"userid": targetID})
+		if err != nil || n == 0 {
+			return "", err
+		}
+		return targetID, nil
+	}
+
+	t, err := resolveTarget(targetType, targetID)
+	if err != nil {
+		return "", err
+	}
+	doc, err := t.load(ctx)
+	if err != nil {
+		// Deleted content is only left on the action that removed it.
+		active, findErr := activeTakeDowns(ctx, targetType, targetID)
+		if findErr != nil || len(active) == 0 {
+			return "", err
+		}
+		return active[len(active)-1].UserID, nil
+	}
+	return t.author(doc), nil
+}
+
+func activeTakeDownFilter(targetType, targetID string) bson.M {
+	return bson.M{
+		"targetType": targetType,
+		"targetId":   targetID,
+		"actions": bson.M{"$elemMatch": bson.M{
+			"action":     bson.M{"$in": []string{"hide", "delete"}},
+			"revertedAt": nil,
+		}},
+	}
+}
+
+// activeTakeDowns returns the hide and delete actions in force on the
+// target, oldest first. A target has at most one, except that held or hidden
+// content may additionally be deleted (see canStack).
+func activeTakeDowns(ctx context.Context, targetType, targetID string) ([]models.ModerationAction, error) {
+	cursor, err := db.ReportsCollection.Find(ctx, activeTakeDownFilter(targetType, targetID))
+	if err != nil {
+		return nil, err
+	}
+	var reports []models.Report
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+
+	var active []models.ModerationAction
+	seen := map[primitive.ObjectID]bool{}
+	for _, report := range reports {
+		for _, a := range report.Actions {
+			if (a.Action == "hide" || a.Action == "delete") && a.RevertedAt == nil && !seen[a.ID] {
+				seen[a.ID] = true
+				active = append(active, a)
+			}
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].CreatedAt.Before(active[j].CreatedAt) })
+	return active, nil
+}
+
+// canStack reports whether action may be taken while active take-downs are
+// in force. Only deleting hidden content is allowed, so moderators can
+// remove spam the content filter held without publishing it first; the
+// delete's snapshot keeps it hidden if the delete is ever reverted.
+func canStack(action models.ModerationAction, active []models.ModerationAction) bool {
+	if len(active) == 0 {
+		return true
+	}
+	if action.Action != "delete" || action.TargetType == "farm" {
+		return false
+	}
+	for _, a := range active {
+		if a.Action != "hide" {
+			return false
+		}
+	}
+	return true
+}
+
+func findAction(ctx context.Context, targetType, targetID string, actionID primitive.ObjectID) (models.ModerationAction, error) {
+	var report models.Report
+	err := db.ReportsCollection.FindOne(ctx, bson.M{
+		"targetType": targetType,
+		"targetId":   targetID,
+		"actions.id": actionID,
+	}).Decode(&report)
+	if err != nil {
+		return models.ModerationAction{}, err
+	}
+	for _, a := range report.Actions {
+		if a.ID == actionID {
+			return a, nil
+		}
+	}
+	return models.ModerationAction{}, mongo.ErrNoDocuments
+}
+
+// recordAction appends the action to every report about the target and
+// resolves the ones still open, so reports.NotifyReporters tells the
+// reporters.
+func recordAction(ctx context.Context, action models.ModerationAction) error {
+	filter := bson.M{"targetType": action.TargetType, "targetId": action.TargetID}
+	_, err := db.ReportsCollection.UpdateMany(ctx, filter, bson.M{
+		"$push": bson.M{"actions": action},
+		"$set":  bson.M{"updatedAt": action.CreatedAt},
+	})
+	if err != nil {
+		return err
+	}
+
+	filter["status"] = bson.M{"$nin": closedReportStatuses}
+	_, err = db.ReportsCollection.UpdateMany(ctx, filter, bson.M{
+		"$set": bson.M{
+			"status":     "resolved",
+			"reviewedBy": action.ModeratorID,
+			"notified":   false,
+		},
+		"$unset": bson.M{"notifiedInApp": "", "notifyAttempts": "", "nextNotifyAt": ""},
+	})
+	return err
+}
@@ -0,0 +1,208 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"naevis/comments"
+	"naevis/db"
+	"naevis/models"
+	"naevis/mq"
+	"naevis/reviews"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var errUnsupportedTarget = errors.New("unsupported target type")
+
+// contentTarget says where a reported item is stored and which fields may
+// hold its author. Chat messages come in two shapes, hence two fields.
+type contentTarget struct {
+	coll    *mongo.Collection
+	filter  bson.M
+	authors []string
+}
+
+func resolveTarget(targetType, targetID string) (contentTarget, error) {
+	if targetType == "review" {
+		return contentTarget{db.ReviewsCollection, bson.M{"reviewid": targetID}, []string{"userid"}}, nil
+	}
+
+	oid, err := primitive.ObjectIDFromHex(targetID)
+	if err != nil {
+		return contentTarget{}, errUnsupportedTarget
+	}
+	switch targetType {
+	case "comment":
+		return contentTarget{db.CommentsCollection, bson.M{"_id": oid}, []string{"created_by"}}, nil
+	case "recipe":
+		return contentTarget{db.RecipeCollection, bson.M{"_id": oid}, []string{"userId"}}, nil
+	case "farm":
+		return contentTarget{db.FarmsCollection, bson.M{"_id": oid}, []string{"createdBy"}}, nil
+	case "message":
+		return contentTarget{db.MessagesCollection, bson.M{"_id": oid}, []string{"sender", "userID"}}, nil
+	}
+	return contentTarget{}, errUnsupportedTarget
+}
+
+func (t contentTarget) load(ctx context.Context) (bson.Raw, error) {
+	return t.coll.FindOne(ctx, t.filter).Raw()
+}
+
+func (t contentTarget) author(doc bson.Raw) string {
+	for _, field := range t.authors {
+		if v, ok := doc.Lookup(field).StringValueOK(); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func isVisible(doc bson.Raw) bool {
+	hidden, _ := doc.Lookup("hidden").BooleanOK()
+	return !hidden
+}
+
+// takeDown hides or deletes the target. Hidden content is flagged and
+// filtered out by its readers; deleted content is removed and kept on the
+// action as a snapshot. Farms go through their usual soft delete instead,
+// with a hold so the owner cannot restore them.
+func takeDown(ctx context.Context, action *models.ModerationAction, t contentTarget, doc bson.Raw) error {
+	if action.TargetType == "farm" {
+		return holdFarm(ctx, action, doc)
+	}
+
+	var err error
+	if action.Action == "hide" {
+		_, err = t.coll.UpdateOne(ctx, t.filter, bson.M{"$set": bson.M{"hidden": true}})
+	} else {
+		action.Snapshot = doc
+		_, err = t.coll.DeleteOne(ctx, t.filter)
+	}
+	if err != nil {
+		return err
+	}
+	if isVisible(doc) {
+		visibilityChanged(ctx, action.TargetType, doc, false)
+	}
+	return nil
+}
+
+// restore undoes takeDown.
+func restore(ctx context.Context, action models.ModerationAction, t contentTarget) error {
+	if action.TargetType == "farm" {
+		return releaseFarm(ctx, action)
+	}
+
+	if action.Action == "hide" {
+		res, err := t.coll.UpdateOne(ctx, t.filter, bson.M{"$unset": bson.M{"hidden": ""}})
+		if err != nil || res.ModifiedCount == 0 {
+			return err
+		}
+		if doc, err := t.load(ctx); err == nil {
+			visibilityChanged(ctx, action.TargetType, doc, true)
+		}
+		return nil
+	}
+
+	if _, err := t.coll.InsertOne(ctx, action.Snapshot); err != nil {
+		return err
+	}
+	if isVisible(action.Snapshot) {
+		visibilityChanged(ctx, action.TargetType, action.Snapshot, true)
+	}
+	return nil
+}
+
+// visibilityChanged keeps derived data in step when content disappears or
+// comes back: review ratings and cached comment counts.
+func visibilityChanged(ctx context.Context, targetType string, doc bson.Raw, visible bool) {
+	entityType, _ := doc.Lookup("entity_type").StringValueOK()
+	entityID, _ := doc.Lookup("entity_id").StringValueOK()
+
+	switch targetType {
+	case "review":
+		rating, ok := doc.Lookup("rating").AsInt64OK()
+		if !ok || rating <= 0 {
+			return
+		}
+		old, updated := int(rating), 0
+		if visible {
+			old, updated = 0, int(rating)
+		}
+		if err := reviews.AdjustRating(ctx, entityType, entityID, old, updated); err != nil {
+			log.Printf("Moderation: rating adjust failed for %s %s: %v", entityType, entityID, err)
+		}
+	case "comment":
+		comments.InvalidateCommentCount(entityType, entityID)
+	}
+}
+
+// holdFarm soft-deletes a farm and its crops the way farms.DeleteFarm does,
+// and marks it held. Farms already deleted by their owner just get the hold.
+func holdFarm(ctx context.Context, action *models.ModerationAction, doc bson.Raw) error {
+	farmID := doc.Lookup("_id").ObjectID()
+	set := bson.M{"moderationHold": action.Action}
+
+	alreadyDeleted := doc.Lookup("deletedAt").Type == bson.TypeDateTime
+	if !alreadyDeleted {
+		set["deletedAt"] = action.CreatedAt
+	}
+	if _, err := db.FarmsCollection.UpdateOne(ctx, bson.M{"_id": farmID}, bson.M{"$set": set}); err != nil {
+		return err
+	}
+	if alreadyDeleted {
+		return nil
+	}
+
+	_, err := db.CropsCollection.UpdateMany(ctx,
+		bson.M{"farmId": farmID, "deletedAt": nil},
+		bson.M{"$set": bson.M{"deletedAt": action.CreatedAt}},
+	)
+	if err != nil {
+		return err
+	}
+	go mq.Emit("farm-deleted", mq.Index{EntityType: "farm", EntityId: farmID.Hex(), Method: "DELETE"})
+	return nil
+}
+
+// releaseFarm lifts the hold, and restores the farm and the crops that went
+// down with it if the moderator was the one who deleted it.
+func releaseFarm(ctx context.Context, action models.ModerationAction) error {
+	farmID, err := primitive.ObjectIDFromHex(action.TargetID)
+	if err != nil {
+		return err
+	}
+
+	var farm models.Farm
+	if err := db.FarmsCollection.FindOne(ctx, bson.M{"_id": farmID}).Decode(&farm); err != nil {
+		return err
+	}
+
+	update := bson.M{"$unset": bson.M{"moderationHold": ""}}
+	ownDelete := farm.DeletedAt != nil && farm.DeletedAt.Equal(action.CreatedAt)
+	if ownDelete {
+		update["$unset"] = bson.M{"moderationHold": "", "deletedAt": ""}
+		update["$set"] = bson.M{"updatedAt": time.Now()}
+	}
+	if _, err := db.FarmsCollection.UpdateOne(ctx, bson.M{"_id": farmID}, update); err != nil {
+		return err
+	}
+	if !ownDelete {
+		return nil
+	}
+
+	_, err = db.CropsCollection.UpdateMany(ctx,
+		bson.M{"farmId": farmID, "deletedAt": farm.DeletedAt},
+		bson.M{"$unset": bson.M{"deletedAt": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	go mq.Emit("farm-restored", mq.Index{EntityType: "farm", EntityId: farmID.Hex(), Method: "POST"})
+	return nil
+}
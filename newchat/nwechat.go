@@ -43,6 +43,7 @@ type Client struct {
 	Send   chan []byte
 	Room   string
 	UserID string
+	claims *middleware.Claims // rechecked on every message
 }
 
 type broadcastMsg struct {
@@ -248,6 +249,7 @@ func WebSocketHandler(hub *Hub) httprouter.Handle {
 			Send:   make(chan []byte, 256),
 			Room:   room,
 			UserID: userID,
+			claims: claims,
 		}
 
 		// Send last 20 messages to this client, oldest→newest:
@@ -315,6 +317,9 @@ func readPump(c *Client, hub *Hub) {
 		if err != nil {
 			break
 		}
+		if middleware.CheckAccess(c.claims) != nil {
+			break
+		}
 
 		var in inboundPayload
 		if err := json.Unmarshal(raw, &in); err != nil {
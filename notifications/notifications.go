@@ -0,0 +1,146 @@
+package notifications
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Notify stores an in-app notification for userID. Failures are logged and
+// swallowed so callers can fire it from a goroutine.
+func Notify(userID, kind, title, body, entityType, entityID string) {
+	if err := Send(context.Background(), userID, kind, title, body, entityType, entityID); err != nil {
+		log.Printf("Notify %s for %s failed: %v", kind, userID, err)
+	}
+}
+
+// Send is Notify for callers that retry on failure.
+func Send(ctx context.Context, userID, kind, title, body, entityType, entityID string) error {
+	if userID == "" {
+		return nil
+	}
+	n := models.Notification{
+		UserID:     userID,
+		Type:       kind,
+		Title:      title,
+		Body:       body,
+		EntityType: entityType,
+		EntityID:   entityID,
+		CreatedAt:  time.Now(),
+	}
+	_, err := db.NotificationsCollection.InsertOne(ctx, n)
+	return err
+}
+
+// GET /api/v1/notifications?unread=true&page=1&limit=20
+func GetNotifications(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page := utils.ParseInt(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+	limit := utils.ParseInt(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	filter := bson.M{"userId": userID}
+	if r.URL.Query().Get("unread") == "true" {
+		filter["read"] = false
+	}
+
+	opts := options.Find().
+		SetSort(bson.M{"createdAt": -1}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+
+	cursor, err := db.NotificationsCollection.Find(r.Context(), filter, opts)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to fetch notifications"})
+		return
+	}
+	defer cursor.Close(r.Context())
+
+	notifications := []models.Notification{}
+	if err := cursor.All(r.Context(), &notifications); err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to decode notifications"})
+		return
+	}
+
+	unread, _ := db.NotificationsCollection.CountDocuments(r.Context(), bson.M{"userId": userID, "read": false})
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{
+		"success":       true,
+		"notifications": notifications,
+		"unread":        unread,
+		"page":          page,
+		"limit":         limit,
+	})
+}
+
+// PUT /api/v1/notifications/:id
+//
+// Marks a single notification as read.
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid notification ID"})
+		return
+	}
+
+	res, err := db.NotificationsCollection.UpdateOne(r.Context(),
+		bson.M{"_id": id, "userId": userID},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false})
+		return
+	}
+	if res.MatchedCount == 0 {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Notification not found"})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+}
+
+// POST /api/v1/notifications/read-all
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	res, err := db.NotificationsCollection.UpdateMany(r.Context(),
+		bson.M{"userId": userID, "read": false},
+		bson.M{"$set": bson.M{"read": true}},
+	)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false})
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "updated": res.ModifiedCount})
+}
@@ -0,0 +1,105 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"naevis/oidc"
+	"naevis/oidc/oidctest"
+)
+
+const callback = "http://localhost:3000/oidc/callback"
+
+func startIssuer(t *testing.T) *oidctest.Issuer {
+	t.Helper()
+	iss, err := oidctest.Start("farmium")
+	if err != nil {
+		t.Fatalf("starting mock issuer: %v", err)
+	}
+	t.Cleanup(iss.Close)
+	return iss
+}
+
+// signIn runs the code flow up to the callback and returns the code.
+func signIn(t *testing.T, iss *oidctest.Issuer, p *oidc.Provider, state, nonce, verifier, hint string) string {
+	t.Helper()
+	authURL, err := p.AuthURL(context.Background(), state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("AuthURL: %v", err)
+	}
+	if hint != "" {
+		authURL += "&login_hint=" + url.QueryEscape(hint)
+	}
+	code, gotState, err := iss.Authorize(authURL)
+	if err != nil {
+		t.Fatalf("Authorize: %v", err)
+	}
+	if gotState != state {
+		t.Fatalf("state = %q, want %q", gotState, state)
+	}
+	return code
+}
+
+func TestCodeFlow(t *testing.T) {
+	iss := startIssuer(t)
+	oidc.Register(iss.Provider("mock", callback))
+	p, err := oidc.Get("mock")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	code := signIn(t, iss, p, "state-1", "nonce-1", "verifier-1", "")
+	id, err := p.Exchange(context.Background(), code, "verifier-1", "nonce-1")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	want := oidc.Identity{
+		Provider:          "mock",
+		Subject:           iss.User.Subject,
+		Email:             iss.User.Email,
+		EmailVerified:     true,
+		Name:              iss.User.Name,
+		PreferredUsername: iss.User.Username,
+	}
+	if *id != want {
+		t.Errorf("identity = %+v, want %+v", *id, want)
+	}
+
+	// Codes are single use.
+	if _, err := p.Exchange(context.Background(), code, "verifier-1", "nonce-1"); err == nil {
+		t.Error("second Exchange of the same code succeeded")
+	}
+}
+
+func TestLoginHint(t *testing.T) {
+	iss := startIssuer(t)
+	p := iss.Provider("mock", callback)
+
+	code := signIn(t, iss, p, "s", "n", "v", "ada@example.com")
+	id, err := p.Exchange(context.Background(), code, "v", "n")
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if id.Email != "ada@example.com" || id.Subject == iss.User.Subject {
+		t.Errorf("identity = %+v, want the hinted user", *id)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	iss := startIssuer(t)
+	p := iss.Provider("mock", callback)
+
+	t.Run("wrong verifier", func(t *testing.T) {
+		code := signIn(t, iss, p, "s", "n", "v", "")
+		if _, err := p.Exchange(context.Background(), code, "other", "n"); err == nil {
+			t.Error("Exchange accepted a code with the wrong PKCE verifier")
+		}
+	})
+	t.Run("wrong nonce", func(t *testing.T) {
+		code := signIn(t, iss, p, "s", "n", "v", "")
+		if _, err := p.Exchange(context.Background(), code, "v", "other"); err == nil {
+			t.Error("Exchange accepted an ID token with the wrong nonce")
+		}
+	})
+}
@@ -0,0 +1,257 @@
+// Package oidctest is a minimal OpenID Connect issuer for development and
+// tests. It serves discovery, keys, authorization and token endpoints,
+// signs in whoever asks without a login page, and checks PKCE the way a
+// real provider would.
+//
+//	iss, _ := oidctest.Start("farmium")
+//	defer iss.Close()
+//	oidc.Register(iss.Provider("mock", "http://localhost:3000/oidc/callback"))
+//
+// The user signed in is User, or for a login_hint of an email address, a
+// user made from that address.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"naevis/oidc"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const keyID = "oidctest"
+
+// User is an account at the mock issuer.
+type User struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Username      string
+}
+
+type grant struct {
+	user        User
+	clientID    string
+	redirectURI string
+	challenge   string
+	nonce       string
+	expires     time.Time
+}
+
+// Issuer is the mock provider. URL must be its externally visible base
+// URL; Start sets it.
+type Issuer struct {
+	URL      string
+	ClientID string
+	User     User
+
+	key    *rsa.PrivateKey
+	server *httptest.Server
+
+	mu    sync.Mutex
+	codes map[string]grant
+}
+
+// NewIssuer creates an issuer for clientID without serving it.
+func NewIssuer(clientID string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+	return &Issuer{
+		ClientID: clientID,
+		User: User{
+			Subject:       "mock-user",
+			Email:         "mock.user@example.com",
+			EmailVerified: true,
+			Name:          "Mock User",
+			Username:      "mockuser",
+		},
+		key:   key,
+		codes: map[string]grant{},
+	}, nil
+}
+
+// Start serves a new issuer on a local test server.
+func Start(clientID string) (*Issuer, error) {
+	iss, err := NewIssuer(clientID)
+	if err != nil {
+		return nil, err
+	}
+	iss.server = httptest.NewServer(iss)
+	iss.URL = iss.server.URL
+	return iss, nil
+}
+
+// Close stops the server Start began.
+func (iss *Issuer) Close() {
+	if iss.server != nil {
+		iss.server.Close()
+	}
+}
+
+// Provider is the configuration that points at this issuer.
+func (iss *Issuer) Provider(name, redirectURL string) *oidc.Provider {
+	return &oidc.Provider{Name: name, Issuer: iss.URL, ClientID: iss.ClientID, RedirectURL: redirectURL}
+}
+
+// Authorize plays the browser: it follows authURL and returns the code and
+// state the issuer redirects back with.
+func (iss *Issuer) Authorize(authURL string) (code, state string, err error) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		return "", "", err
+	}
+	resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		return "", "", errors.New("oidctest: authorization was not redirected")
+	}
+	q := loc.Query()
+	if e := q.Get("error"); e != "" {
+		return "", "", errors.New("oidctest: " + e)
+	}
+	return q.Get("code"), q.Get("state"), nil
+}
+
+func (iss *Issuer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		writeJSON(w, http.StatusOK, map[string]any{
+			"issuer":                                iss.URL,
+			"authorization_endpoint":                iss.URL + "/authorize",
+			"token_endpoint":                        iss.URL + "/token",
+			"jwks_uri":                              iss.URL + "/jwks",
+			"response_types_supported":              []string{"code"},
+			"subject_types_supported":               []string{"public"},
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+			"code_challenge_methods_supported":      []string{"S256"},
+		})
+	case "/jwks":
+		pub := iss.key.PublicKey
+		writeJSON(w, http.StatusOK, map[string]any{"keys": []map[string]string{{
+			"kty": "RSA", "kid": keyID, "alg": "RS256", "use": "sig",
+			"n": base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}}})
+	case "/authorize":
+		iss.authorize(w, r)
+	case "/token":
+		iss.token(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (iss *Issuer) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || q.Get("redirect_uri") == "" || q.Get("client_id") != iss.ClientID {
+		http.Error(w, "invalid client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+	back := redirect.Query()
+	back.Set("state", q.Get("state"))
+	switch {
+	case q.Get("response_type") != "code":
+		back.Set("error", "unsupported_response_type")
+	case q.Get("code_challenge") == "" || q.Get("code_challenge_method") != "S256":
+		back.Set("error", "invalid_request")
+	default:
+		user := iss.User
+		if hint := q.Get("login_hint"); strings.Contains(hint, "@") {
+			name := strings.SplitN(hint, "@", 2)[0]
+			user = User{Subject: "mock-" + hint, Email: hint, EmailVerified: true, Name: name, Username: name}
+		}
+		code := randomString()
+		iss.mu.Lock()
+		iss.codes[code] = grant{
+			user:        user,
+			clientID:    q.Get("client_id"),
+			redirectURI: q.Get("redirect_uri"),
+			challenge:   q.Get("code_challenge"),
+			nonce:       q.Get("nonce"),
+			expires:     time.Now().Add(time.Minute),
+		}
+		iss.mu.Unlock()
+		back.Set("code", code)
+	}
+	redirect.RawQuery = back.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (iss *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	code := r.PostForm.Get("code")
+	iss.mu.Lock()
+	g, ok := iss.codes[code]
+	delete(iss.codes, code) // codes are single use
+	iss.mu.Unlock()
+
+	if !ok || time.Now().After(g.expires) ||
+		r.PostForm.Get("client_id") != g.clientID ||
+		r.PostForm.Get("redirect_uri") != g.redirectURI ||
+		oidc.Challenge(r.PostForm.Get("code_verifier")) != g.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                iss.URL,
+		"sub":                g.user.Subject,
+		"aud":                g.clientID,
+		"iat":                now.Unix(),
+		"exp":                now.Add(5 * time.Minute).Unix(),
+		"email":              g.user.Email,
+		"email_verified":     g.user.EmailVerified,
+		"name":               g.user.Name,
+		"preferred_username": g.user.Username,
+	}
+	if g.nonce != "" {
+		claims["nonce"] = g.nonce
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(iss.key)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func randomString() string {
+	s, err := oidc.RandomString(24)
+	if err != nil {
+		panic("oidctest: crypto/rand failed: " + err.Error())
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,48 @@
+// Package owners resolves which user owns a piece of user-generated content,
+// for checks such as "the farm owner may reply to reviews of the farm".
+package owners
+
+import (
+	"context"
+	"errors"
+
+	"naevis/db"
+	"naevis/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrUnknownOwner is returned for entity types without a known owner field.
+var ErrUnknownOwner = errors.New("entity has no known owner")
+
+// EntityOwner returns the user ID that owns an entity. Crops are owned by
+// the owner of their farm.
+func EntityOwner(ctx context.Context, entityType, entityID string) (string, error) {
+	oid, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return "", ErrUnknownOwner
+	}
+
+	switch entityType {
+	case "farm":
+		var farm models.Farm
+		if err := db.FarmsCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&farm); err != nil {
+			return "", err
+		}
+		return farm.CreatedBy, nil
+	case "crop":
+		var crop models.Crop
+		if err := db.CropsCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&crop); err != nil {
+			return "", err
+		}
+		return EntityOwner(ctx, "farm", crop.FarmID.Hex())
+	case "recipe":
+		var recipe models.Recipe
+		if err := db.RecipeCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&recipe); err != nil {
+			return "", err
+		}
+		return recipe.UserID, nil
+	}
+	return "", ErrUnknownOwner
+}
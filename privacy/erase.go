@@ -0,0 +1,398 @@
+package privacy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"naevis/auth"
+	"naevis/db"
+	"naevis/mailer"
+	"naevis/models"
+	"naevis/mq"
+	"naevis/profile"
+	"naevis/rdx"
+	"naevis/reviews"
+	"naevis/structs"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// DeletedUser stands in for the user ID on records that outlive an erased
+// account.
+const DeletedUser = "deleted-user"
+
+// ErasureGracePeriod is how long a requested erasure waits, so it can be
+// cancelled, before it runs.
+const ErasureGracePeriod = 7 * 24 * time.Hour
+
+// POST /api/v1/privacy/erasure
+//
+// Body: { "password": "..." }, required unless the account signs in only
+// through an external provider. Schedules the caller's account for erasure
+// after ErasureGracePeriod; until then it can be cancelled.
+func RequestErasure(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var input struct {
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil && err != io.EOF {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid input"})
+		return
+	}
+	ctx := r.Context()
+	userID := utils.GetUserIDFromRequest(r)
+
+	var user structs.User
+	if err := db.UserCollection.FindOne(ctx, bson.M{"userid": userID}).Decode(&user); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "User not found"})
+		return
+	}
+	if user.Password != "" {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(input.Password)); err != nil {
+			utils.RespondWithJSON(w, http.StatusUnauthorized, utils.M{"success": false, "message": "Invalid password"})
+			return
+		}
+	}
+	if user.ErasureDueAt != nil {
+		utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "erasureDueAt": user.ErasureDueAt})
+		return
+	}
+
+	due := time.Now().Add(ErasureGracePeriod)
+	_, err := db.UserCollection.UpdateOne(ctx,
+		bson.M{"userid": userID},
+		bson.M{"$set": bson.M{"erasure_due_at": due}},
+	)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to schedule erasure"})
+		return
+	}
+
+	body := "Your account " + user.Username + " and its data will be erased on " +
+		due.UTC().Format(time.RFC1123) + ".\n\nOrders are kept for our accounts without your name or address. " +
+		"To keep your account, sign in and cancel the erasure from your privacy settings before then."
+	go func() {
+		if err := mailer.Send(user.Email, "Your account is scheduled for erasure", body); err != nil {
+			log.Printf("Failed to send erasure notice to %s: %v", user.Email, err)
+		}
+	}()
+
+	utils.RespondWithJSON(w, http.StatusAccepted, utils.M{"success": true, "erasureDueAt": due})
+}
+
+// GET /api/v1/privacy/erasure
+func GetErasure(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var user structs.User
+	if err := db.UserCollection.FindOne(r.Context(), bson.M{"userid": utils.GetUserIDFromRequest(r)}).Decode(&user); err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "User not found"})
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "scheduled": user.ErasureDueAt != nil, "erasureDueAt": user.ErasureDueAt})
+}
+
+// DELETE /api/v1/privacy/erasure
+//
+// Cancels a scheduled erasure.
+func CancelErasure(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	res, err := db.UserCollection.UpdateOne(r.Context(),
+		bson.M{"userid": utils.GetUserIDFromRequest(r), "erasure_due_at": bson.M{"$exists": true}},
+		bson.M{"$unset": bson.M{"erasure_due_at": ""}},
+	)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to cancel erasure"})
+		return
+	}
+	if res.ModifiedCount == 0 {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "No erasure is scheduled"})
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true})
+}
+
+// Erase applies the erasure policy to user. Every step can be repeated, so
+// a failed run is simply retried; the account itself goes last.
+func Erase(ctx context.Context, user structs.User) error {
+	userID := user.UserID
+
+	if err := auth.SignOutEverywhere(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := reviews.EraseUser(ctx, userID); err != nil {
+		return err
+	}
+
+	steps := []func(context.Context, string) error{
+		anonymizeComments,
+		anonymizeMessages,
+		anonymizeOrders,
+		deleteRecipes,
+		deleteFarms,
+		unlinkFollows,
+		deleteOwned,
+		deleteExports,
+	}
+	for _, step := range steps {
+		if err := step(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	removeUserPictures(user)
+	rdx.RdxDel("users:" + userID)
+	rdx.RdxHdel("users", userID)
+	_ = profile.InvalidateCachedProfile(user.Username)
+
+	if _, err := db.UserCollection.DeleteOne(ctx, bson.M{"userid": userID}); err != nil {
+		return err
+	}
+
+	go mq.Emit("profile-deleted", mq.Index{EntityType: "profile", EntityId: userID, Method: "DELETE"})
+	return nil
+}
+
+func anonymizeComments(ctx context.Context, userID string) error {
+	_, err := db.CommentsCollection.UpdateMany(ctx,
+		bson.M{"created_by": userID},
+		bson.M{
+			"$set":   bson.M{"created_by": DeletedUser, "content": "[deleted]", "updated_at": time.Now()},
+			"$unset": bson.M{"mentions": ""},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	_, err = db.CommentsCollection.UpdateMany(ctx,
+		bson.M{"mentions": userID},
+		bson.M{"$pull": bson.M{"mentions": userID}},
+	)
+	return err
+}
+
+// anonymizeMessages blanks the user's messages but leaves them in place so
+// the other side of a conversation keeps its thread. The three chat
+// services name the sender "sender", "senderId" and "userID".
+func anonymizeMessages(ctx context.Context, userID string) error {
+	drop := bson.M{
+		"senderName":  "",
+		"avatarUrl":   "",
+		"media":       "",
+		"caption":     "",
+		"filename":    "",
+		"filetype":    "",
+		"path":        "",
+		"edithistory": "",
+		"text":        "",
+		"fileURL":     "",
+		"fileType":    "",
+	}
+	for _, field := range []string{"sender", "senderId", "userID"} {
+		_, err := db.MessagesCollection.UpdateMany(ctx,
+			bson.M{field: userID},
+			bson.M{"$set": bson.M{field: DeletedUser, "content": "", "deleted": true}, "$unset": drop},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := db.ChatsCollection.UpdateMany(ctx,
+		bson.M{"lastMessage.senderId": userID},
+		bson.M{"$set": bson.M{"lastMessage.senderId": DeletedUser, "lastMessage.text": ""}},
+	); err != nil {
+		return err
+	}
+	_, err := db.ChatsCollection.UpdateMany(ctx,
+		bson.M{"$or": []bson.M{{"participants": userID}, {"users": userID}}},
+		bson.M{"$pull": bson.M{"participants": userID, "users": userID}},
+	)
+	return err
+}
+
+// anonymizeOrders keeps orders for the books but drops who placed them and
+// where they were shipped. Cart orders carry the buyer on every item too, so
+// each order is rewritten whole in one update.
+func anonymizeOrders(ctx context.Context, userID string) error {
+	cursor, err := db.OrderCollection.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return err
+	}
+	var orders []struct {
+		ID           primitive.ObjectID `bson:"_id"`
+		models.Order `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &orders); err != nil {
+		return err
+	}
+	for _, order := range orders {
+		for _, items := range order.Items {
+			for i := range items {
+				if items[i].UserID == userID {
+					items[i].UserID = DeletedUser
+				}
+			}
+		}
+		_, err := db.OrderCollection.UpdateOne(ctx,
+			bson.M{"_id": order.ID},
+			bson.M{"$set": bson.M{"userId": DeletedUser, "address": "", "items": order.Items}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = db.FarmOrdersCollection.UpdateMany(ctx,
+		bson.M{"userId": userID},
+		bson.M{"$set": bson.M{"userId": DeletedUser}},
+	)
+	if err != nil {
+		return err
+	}
+	// Reports are unique per reporter and target, so two erased users who
+	// reported the same thing can't share one placeholder.
+	_, err = db.ReportsCollection.UpdateMany(ctx,
+		bson.M{"reportedBy": userID},
+		bson.M{"$set": bson.M{"reportedBy": DeletedUser + "-" + primitive.NewObjectID().Hex()}},
+	)
+	return err
+}
+
+func deleteRecipes(ctx context.Context, userID string) error {
+	cursor, err := db.RecipeCollection.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return err
+	}
+	var recipes []models.Recipe
+	if err := cursor.All(ctx, &recipes); err != nil {
+		return err
+	}
+	if len(recipes) == 0 {
+		return nil
+	}
+
+	ids := make([]string, 0, len(recipes))
+	for _, recipe := range recipes {
+		ids = append(ids, recipe.ID.Hex())
+	}
+	dependents := bson.M{"entity_type": "recipe", "entity_id": bson.M{"$in": ids}}
+	if _, err := db.ReviewsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.CommentsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.UserDataCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.RatingsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	if _, err := db.ReactionsCollection.DeleteMany(ctx, dependents); err != nil {
+		return err
+	}
+	_, err = db.RecipeCollection.DeleteMany(ctx, bson.M{"userId": userID})
+	return err
+}
+
+// deleteFarms soft-deletes the user's farms and their crops the way
+// farms.DeleteFarm does; farms.PurgeDeletedFarms removes them for good once
+// their orders are closed.
+func deleteFarms(ctx context.Context, userID string) error {
+	cursor, err := db.FarmsCollection.Find(ctx, bson.M{"createdBy": userID, "deletedAt": nil})
+	if err != nil {
+		return err
+	}
+	var farms []models.Farm
+	if err := cursor.All(ctx, &farms); err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, farm := range farms {
+		if _, err := db.FarmsCollection.UpdateOne(ctx,
+			bson.M{"_id": farm.FarmID},
+			bson.M{"$set": bson.M{"deletedAt": now, "updatedAt": now}},
+		); err != nil {
+			return err
+		}
+		if _, err := db.CropsCollection.UpdateMany(ctx,
+			bson.M{"farmId": farm.FarmID, "deletedAt": nil},
+			bson.M{"$set": bson.M{"deletedAt": now}},
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func unlinkFollows(ctx context.Context, userID string) error {
+	if _, err := db.FollowingsCollection.DeleteOne(ctx, bson.M{"userid": userID}); err != nil {
+		return err
+	}
+	_, err := db.FollowingsCollection.UpdateMany(ctx,
+		bson.M{"$or": []bson.M{{"follows": userID}, {"followers": userID}}},
+		bson.M{"$pull": bson.M{"follows": userID, "followers": userID}},
+	)
+	return err
+}
+
+// deleteOwned drops the records that only ever concern the user.
+func deleteOwned(ctx context.Context, userID string) error {
+	owned := []struct {
+		coll   *mongo.Collection
+		filter bson.M
+	}{
+		{db.UserDataCollection, bson.M{"userid": userID}},
+		{db.SettingsCollection, bson.M{"userID": userID}},
+		{db.CartCollection, bson.M{"userId": userID}},
+		{db.CropWatchesCollection, bson.M{"userId": userID}},
+		{db.NotificationsCollection, bson.M{"userId": userID}},
+		{db.ReactionsCollection, bson.M{"userid": userID}},
+		{db.BlocksCollection, bson.M{"$or": []bson.M{{"blocker": userID}, {"blocked": userID}}}},
+		{db.SessionsCollection, bson.M{"userid": userID}},
+		{db.RefreshTokensCollection, bson.M{"userid": userID}},
+		{db.APITokensCollection, bson.M{"userid": userID}},
+		{db.SecurityEventsCollection, bson.M{"userid": userID}},
+	}
+	for _, o := range owned {
+		if _, err := o.coll.DeleteMany(ctx, o.filter); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteExports removes the user's data exports and their files.
+func deleteExports(ctx context.Context, userID string) error {
+	cursor, err := db.DataExportsCollection.Find(ctx, bson.M{"userid": userID})
+	if err != nil {
+		return err
+	}
+	var exports []models.DataExport
+	if err := cursor.All(ctx, &exports); err != nil {
+		return err
+	}
+	for _, export := range exports {
+		removeExportFile(export)
+	}
+	_, err = db.DataExportsCollection.DeleteMany(ctx, bson.M{"userid": userID})
+	return err
+}
+
+func removeUserPictures(user structs.User) {
+	if user.ProfilePicture != "" {
+		_ = os.Remove(filepath.Join("./static/userpic", filepath.Base(user.ProfilePicture)))
+	}
+	if user.BannerPicture != "" {
+		_ = os.Remove(filepath.Join("./static/userpic/banner", filepath.Base(user.BannerPicture)))
+	}
+	_ = os.Remove(filepath.Join("./static/userpic/thumb", filepath.Base(user.UserID)+".jpg"))
+}
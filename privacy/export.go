@@ -0,0 +1,292 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/utils"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// ExportTTL is how long a finished export can be downloaded.
+	ExportTTL = 7 * 24 * time.Hour
+	// exportEvery limits how often a user can start a new export.
+	exportEvery = 24 * time.Hour
+)
+
+// exportDir is where export archives are written. They are served only
+// through DownloadExport, never from ./static.
+func exportDir() string {
+	if dir := os.Getenv("EXPORT_DIR"); dir != "" {
+		return dir
+	}
+	return "./exports"
+}
+
+// source is one file in an export: the documents of coll matching filter,
+// minus the fields in omit.
+type source struct {
+	name   string
+	coll   *mongo.Collection
+	filter func(userID string) bson.M
+	omit   []string
+}
+
+func byField(field string) func(string) bson.M {
+	return func(userID string) bson.M { return bson.M{field: userID} }
+}
+
+// sources lists everything tied to a user ID. Secrets (password and MFA
+// material, token hashes) are left out; they are of no use to the user and
+// would only make a leaked archive worse.
+var sources = []source{
+	{name: "account", coll: db.UserCollection, filter: byField("userid"),
+		omit: []string{"password", "password_hash", "mfa_secret", "mfa_pending_secret", "mfa_last_step", "mfa_recovery_codes"}},
+	{name: "settings", coll: db.SettingsCollection, filter: byField("userID")},
+	{name: "follows", coll: db.FollowingsCollection, filter: byField("userid")},
+	{name: "userdata", coll: db.UserDataCollection, filter: byField("userid")},
+	{name: "farms", coll: db.FarmsCollection, filter: byField("createdBy")},
+	{name: "recipes", coll: db.RecipeCollection, filter: byField("userId")},
+	{name: "comments", coll: db.CommentsCollection, filter: byField("created_by")},
+	{name: "reviews", coll: db.ReviewsCollection, filter: byField("userid")},
+	{name: "review-votes", coll: db.ReviewVotesCollection, filter: byField("userid")},
+	{name: "reactions", coll: db.ReactionsCollection, filter: byField("userid")},
+	{name: "chats", coll: db.ChatsCollection, filter: func(userID string) bson.M {
+		return bson.M{"$or": []bson.M{{"participants": userID}, {"users": userID}}}
+	}},
+	{name: "messages", coll: db.MessagesCollection, filter: func(userID string) bson.M {
+		return bson.M{"$or": []bson.M{{"sender": userID}, {"senderId": userID}, {"userID": userID}}}
+	}},
+	{name: "cart", coll: db.CartCollection, filter: byField("userId")},
+	{name: "orders", coll: db.OrderCollection, filter: byField("userId")},
+	{name: "farm-orders", coll: db.FarmOrdersCollection, filter: byField("userId")},
+	{name: "crop-watches", coll: db.CropWatchesCollection, filter: byField("userId")},
+	{name: "notifications", coll: db.NotificationsCollection, filter: byField("userId")},
+	{name: "reports", coll: db.ReportsCollection, filter: byField("reportedBy")},
+	{name: "blocks", coll: db.BlocksCollection, filter: byField("blocker")},
+	{name: "sessions", coll: db.SessionsCollection, filter: byField("userid")},
+	{name: "api-tokens", coll: db.APITokensCollection, filter: byField("userid"), omit: []string{"hash"}},
+	{name: "security-events", coll: db.SecurityEventsCollection, filter: byField("userid")},
+}
+
+// buildExport writes the archive for export and returns its path and size.
+func buildExport(ctx context.Context, export models.DataExport) (string, int64, error) {
+	if err := os.MkdirAll(exportDir(), 0700); err != nil {
+		return "", 0, err
+	}
+	path := filepath.Join(exportDir(), export.ID.Hex()+".zip")
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", 0, err
+	}
+	defer os.Remove(tmp)
+
+	zw := zip.NewWriter(f)
+	counts := make(map[string]int, len(sources))
+	for _, src := range sources {
+		n, err := writeSource(ctx, zw, src, export.UserID)
+		if err != nil {
+			f.Close()
+			return "", 0, fmt.Errorf("%s: %w", src.name, err)
+		}
+		counts[src.name] = n
+	}
+	if err := writeReadme(zw, export, counts); err != nil {
+		f.Close()
+		return "", 0, err
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return "", 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return "", 0, err
+	}
+	if err := f.Close(); err != nil {
+		return "", 0, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", 0, err
+	}
+	return path, info.Size(), nil
+}
+
+// writeSource adds <name>.json to the archive: a JSON array of the
+// matching documents in relaxed extended JSON.
+func writeSource(ctx context.Context, zw *zip.Writer, src source, userID string) (int, error) {
+	cursor, err := src.coll.Find(ctx, src.filter(userID))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	w, err := zw.Create(src.name + ".json")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return 0, err
+	}
+	n := 0
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return n, err
+		}
+		for _, field := range src.omit {
+			delete(doc, field)
+		}
+		raw, err := bson.MarshalExtJSON(doc, false, false)
+		if err != nil {
+			return n, err
+		}
+		var out bytes.Buffer
+		if n > 0 {
+			out.WriteString(",")
+		}
+		out.WriteString("\n  ")
+		if err := json.Indent(&out, raw, "  ", "  "); err != nil {
+			return n, err
+		}
+		if _, err := w.Write(out.Bytes()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	if err := cursor.Err(); err != nil {
+		return n, err
+	}
+	_, err = io.WriteString(w, "\n]\n")
+	return n, err
+}
+
+func writeReadme(zw *zip.Writer, export models.DataExport, counts map[string]int) error {
+	w, err := zw.Create("README.txt")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Data export for user %s\n", export.UserID)
+	fmt.Fprintf(w, "Generated %s\n\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintln(w, "Each file is a JSON array of records (MongoDB extended JSON).")
+	fmt.Fprintln(w, "Passwords, two-factor secrets and token hashes are not included.")
+	fmt.Fprintln(w)
+	for _, src := range sources {
+		fmt.Fprintf(w, "%-22s %d\n", src.name+".json", counts[src.name])
+	}
+	return nil
+}
+
+func removeExportFile(export models.DataExport) {
+	if export.File != "" {
+		_ = os.Remove(export.File)
+	}
+}
+
+// POST /api/v1/privacy/export
+//
+// Queues a ZIP of everything the platform holds about the caller. One
+// export a day: asking again returns the one already under way or ready.
+func RequestExport(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	ctx := r.Context()
+	userID := utils.GetUserIDFromRequest(r)
+
+	var recent models.DataExport
+	err := db.DataExportsCollection.FindOne(ctx, bson.M{
+		"userid":    userID,
+		"status":    bson.M{"$ne": "failed"},
+		"createdAt": bson.M{"$gt": time.Now().Add(-exportEvery)},
+	}).Decode(&recent)
+	if err == nil {
+		utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "export": recent})
+		return
+	}
+	if err != mongo.ErrNoDocuments {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to request export"})
+		return
+	}
+
+	export := models.DataExport{
+		UserID:    userID,
+		Status:    "pending",
+		CreatedAt: time.Now(),
+	}
+	res, err := db.DataExportsCollection.InsertOne(ctx, export)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to request export"})
+		return
+	}
+	export.ID = res.InsertedID.(primitive.ObjectID)
+	wake()
+
+	utils.RespondWithJSON(w, http.StatusAccepted, utils.M{"success": true, "export": export})
+}
+
+// GET /api/v1/privacy/export
+//
+// The caller's exports, newest first.
+func GetExports(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	opts := options.Find().SetSort(bson.M{"createdAt": -1}).SetLimit(20)
+	cursor, err := db.DataExportsCollection.Find(r.Context(), bson.M{"userid": utils.GetUserIDFromRequest(r)}, opts)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to load exports"})
+		return
+	}
+	exports := []models.DataExport{}
+	if err := cursor.All(r.Context(), &exports); err != nil {
+		utils.RespondWithJSON(w, http.StatusInternalServerError, utils.M{"success": false, "message": "Failed to load exports"})
+		return
+	}
+	utils.RespondWithJSON(w, http.StatusOK, utils.M{"success": true, "exports": exports})
+}
+
+// GET /api/v1/privacy/export/:id/download
+func DownloadExport(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	id, err := primitive.ObjectIDFromHex(ps.ByName("id"))
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusBadRequest, utils.M{"success": false, "message": "Invalid export ID"})
+		return
+	}
+	var export models.DataExport
+	err = db.DataExportsCollection.FindOne(r.Context(), bson.M{"_id": id, "userid": utils.GetUserIDFromRequest(r)}).Decode(&export)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusNotFound, utils.M{"success": false, "message": "Export not found"})
+		return
+	}
+	if export.Status != "ready" || export.ExpiresAt == nil || !export.ExpiresAt.After(time.Now()) {
+		utils.RespondWithJSON(w, http.StatusConflict, utils.M{"success": false, "message": "Export is not available"})
+		return
+	}
+
+	f, err := os.Open(export.File)
+	if err != nil {
+		utils.RespondWithJSON(w, http.StatusGone, utils.M{"success": false, "message": "Export is not available"})
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="farmium-export-`+export.CreatedAt.Format("2006-01-02")+`.zip"`)
+	w.Header().Set("Content-Length", strconv.FormatInt(export.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	io.Copy(w, f)
+}
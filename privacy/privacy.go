@@ -0,0 +1,36 @@
+// Package privacy gives users a copy of their data and erases it when they
+// close their account.
+//
+// Exports are queued by RequestExport and built by Run into a ZIP with one
+// JSON file per kind of record (see sources). They can be downloaded for
+// ExportTTL and are then removed.
+//
+// Erasure is requested with RequestErasure and carried out by Run once
+// ErasureGracePeriod has passed. Erase applies this policy:
+//
+//	users                    deleted, last, with profile pictures and caches
+//	userdata, settings, cart,
+//	cropwatches, notifications,
+//	reactions, blocks        deleted
+//	sessions, refreshtokens,
+//	apitokens, securityevents deleted, after signing out everywhere
+//	dataexports              deleted with their files
+//	followings               the user's own list deleted; removed from others'
+//	reviews, reviewvotes     deleted, rating and vote counts adjusted; owner
+//	                         responses removed (reviews.EraseUser)
+//	recipes                  deleted with their reviews, comments and reactions
+//	farms, crops             soft-deleted; farms.PurgeDeletedFarms removes them
+//	                         once their orders are closed
+//	comments                 kept as "[deleted]" by DeletedUser so replies
+//	                         still make sense; mentions of the user removed
+//	messages                 kept as blank, deleted messages by DeletedUser so
+//	                         the other side's thread stays intact
+//	chats                    the user is removed from the participants
+//	orders                   kept for accounting; owner and item owners set to
+//	                         DeletedUser, the shipping address cleared
+//	forders                  kept for the farms' books; owner set to DeletedUser
+//	reports                  kept for moderation; reporter set to DeletedUser
+//	                         plus a suffix unique to the erasure
+//
+// Analytics and activities hold no user ID and are left alone.
+package privacy
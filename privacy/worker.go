@@ -0,0 +1,152 @@
+package privacy
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/notifications"
+	"naevis/structs"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// staleExport is how long a running export may go without finishing before
+// another worker takes it over, e.g. after a restart.
+const staleExport = 30 * time.Minute
+
+var wakeup = make(chan struct{}, 1)
+
+// wake asks Run to look for work now rather than on its next tick.
+func wake() {
+	select {
+	case wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// Run builds queued data exports, carries out erasures that are due and
+// removes expired export files. Start it once from main.
+func Run() {
+	ticker := time.NewTicker(time.Minute)
+	for {
+		runExports()
+		runErasures()
+		expireExports()
+
+		select {
+		case <-ticker.C:
+		case <-wakeup:
+		}
+	}
+}
+
+func runExports() {
+	for {
+		export, ok := claimExport()
+		if !ok {
+			return
+		}
+		processExport(export)
+	}
+}
+
+// claimExport marks the oldest pending export as running and returns it.
+func claimExport() (models.DataExport, bool) {
+	now := time.Now()
+	filter := bson.M{"$or": []bson.M{
+		{"status": "pending"},
+		{"status": "running", "startedAt": bson.M{"$lt": now.Add(-staleExport)}},
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"createdAt": 1}).
+		SetReturnDocument(options.After)
+
+	var export models.DataExport
+	err := db.DataExportsCollection.FindOneAndUpdate(context.Background(), filter,
+		bson.M{"$set": bson.M{"status": "running", "startedAt": now}}, opts,
+	).Decode(&export)
+	return export, err == nil
+}
+
+func processExport(export models.DataExport) {
+	ctx, cancel := context.WithTimeout(context.Background(), staleExport)
+	defer cancel()
+
+	path, size, err := buildExport(ctx, export)
+	if err != nil {
+		log.Printf("Data export %s failed: %v", export.ID.Hex(), err)
+		db.DataExportsCollection.UpdateOne(context.Background(),
+			bson.M{"_id": export.ID},
+			bson.M{"$set": bson.M{"status": "failed", "error": "The export could not be built"}},
+		)
+		return
+	}
+
+	now := time.Now()
+	_, err = db.DataExportsCollection.UpdateOne(context.Background(),
+		bson.M{"_id": export.ID},
+		bson.M{"$set": bson.M{
+			"status":    "ready",
+			"file":      path,
+			"size":      size,
+			"readyAt":   now,
+			"expiresAt": now.Add(ExportTTL),
+		}},
+	)
+	if err != nil {
+		log.Printf("Data export %s update failed: %v", export.ID.Hex(), err)
+		return
+	}
+
+	notifications.Notify(export.UserID, "data-export",
+		"Your data export is ready",
+		"Download it from your privacy settings within 7 days.",
+		"data-export", export.ID.Hex(),
+	)
+}
+
+func runErasures() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	cursor, err := db.UserCollection.Find(ctx, bson.M{"erasure_due_at": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		log.Println("Erasure find error:", err)
+		return
+	}
+	var users []structs.User
+	if err := cursor.All(ctx, &users); err != nil {
+		log.Println("Erasure decode error:", err)
+		return
+	}
+	for _, user := range users {
+		if err := Erase(ctx, user); err != nil {
+			log.Printf("Erasure of %s failed, will retry: %v", user.UserID, err)
+		}
+	}
+}
+
+func expireExports() {
+	ctx := context.Background()
+	cursor, err := db.DataExportsCollection.Find(ctx, bson.M{"status": "ready", "expiresAt": bson.M{"$lte": time.Now()}})
+	if err != nil {
+		log.Println("Export expiry find error:", err)
+		return
+	}
+	var exports []models.DataExport
+	if err := cursor.All(ctx, &exports); err != nil {
+		log.Println("Export expiry decode error:", err)
+		return
+	}
+	for _, export := range exports {
+		removeExportFile(export)
+		db.DataExportsCollection.UpdateOne(ctx,
+			bson.M{"_id": export.ID},
+			bson.M{"$set": bson.M{"status": "expired"}, "$unset": bson.M{"file": ""}},
+		)
+	}
+}
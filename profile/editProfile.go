@@ -2,8 +2,9 @@ package profile
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"naevis/db"
 	"naevis/middleware"
@@ -15,6 +16,10 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// ErrEmailTaken is returned by UpdateProfileFields when the new email
+// belongs to another account.
+var ErrEmailTaken = errors.New("email already in use")
+
 // EditProfile allows a user to update their own profile fields.
 func EditProfile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	// 1. Extract and validate the JWT from the Authorization header (strip "Bearer " if present).
@@ -45,7 +50,10 @@ func EditProfile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	_ = UpdateCachedUsername(claims.UserID)
 	// 4. Build a bson.M of all fields user wants to update.
 	updates, err := UpdateProfileFields(r, claims)
-	if err != nil {
+	if errors.Is(err, ErrEmailTaken) {
+		http.Error(w, "An account with this email already exists", http.StatusConflict)
+		return
+	} else if err != nil {
 		http.Error(w, "Failed to update profile fields", http.StatusInternalServerError)
 		return
 	}
@@ -77,49 +85,6 @@ func EditProfile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	}
 }
 
-// DeleteProfile deletes the authenticated user’s profile completely.
-func DeleteProfile(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
-	// 1. Extract and validate the JWT from the Authorization header (strip "Bearer " if present).
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-	tokenString := authHeader
-	// if strings.HasPrefix(authHeader, "Bearer ") {
-	// 	tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-	// }
-	claims, err := middleware.ValidateJWT(tokenString)
-	if err != nil {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// 2. Invalidate the cached profile JSON in Redis.
-	_ = InvalidateCachedProfile(claims.Username)
-
-	// 3. Remove the user document from MongoDB by userID.
-	if err := DeleteUserByID(claims.UserID); err != nil {
-		http.Error(w, "Failed to delete profile", http.StatusInternalServerError)
-		return
-	}
-
-	// 4. Emit a “profile-deleted” event asynchronously.
-	m := mq.Index{
-		EntityType: "profile",
-		EntityId:   claims.UserID,
-		Method:     "DELETE",
-	}
-	go mq.Emit("profile-deleted", m)
-
-	// 5. Return a simple JSON success message.
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{
-		"message": "Profile deleted successfully",
-	})
-}
-
 // UpdateProfileFields inspects form values (and potentially uploaded files)
 // to assemble a bson.M of fields that should be updated for this user.
 func UpdateProfileFields(r *http.Request, claims *middleware.Claims) (bson.M, error) {
@@ -134,8 +99,15 @@ func UpdateProfileFields(r *http.Request, claims *middleware.Claims) (bson.M, er
 		}
 	}
 
-	// Email change
-	if newEmail := r.FormValue("email"); newEmail != "" {
+	// Email change; addresses are unique, so one already in use is refused.
+	if newEmail := strings.TrimSpace(r.FormValue("email")); newEmail != "" {
+		n, err := db.UserCollection.CountDocuments(r.Context(), bson.M{"email": newEmail, "userid": bson.M{"$ne": claims.UserID}})
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			return nil, ErrEmailTaken
+		}
 		update["email"] = newEmail
 	}
 
@@ -202,12 +174,3 @@ func UpdateUserByUsername(username string, update bson.M) error {
 	)
 	return err
 }
-
-// DeleteUserByID removes a user document by its userID field.
-func DeleteUserByID(userID string) error {
-	_, err := db.UserCollection.DeleteOne(
-		context.TODO(),
-		bson.M{"userid": userID},
-	)
-	return err
-}
@@ -3,8 +3,10 @@ package profile
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"naevis/blocks"
 	"naevis/db"
 	"naevis/globals"
 	"naevis/middleware"
@@ -50,7 +52,10 @@ func HandleFollowAction(w http.ResponseWriter, r *http.Request, ps httprouter.Pa
 	currentUserID := r.Context().Value(globals.UserIDKey).(string)
 	targetUserID := ps.ByName("id")
 
-	if err := UpdateFollowRelationship(currentUserID, targetUserID, action); err != nil {
+	if err := UpdateFollowRelationship(currentUserID, targetUserID, action); errors.Is(err, ErrBlocked) {
+		http.Error(w, "Cannot follow this user", http.StatusForbidden)
+		return
+	} else if err != nil {
 		log.Printf("Error updating follow relationship: %v", err)
 		http.Error(w, "Failed to update follow relationship", http.StatusInternalServerError)
 		return
@@ -143,10 +148,16 @@ func DoesFollow(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// ErrBlocked is returned when one user has blocked the other.
+var ErrBlocked = errors.New("user is blocked")
+
 func UpdateFollowRelationship(currentUserID, targetUserID, action string) error {
 	if action != "follow" && action != "unfollow" {
 		return fmt.Errorf("invalid action: %s", action)
 	}
+	if action == "follow" && blocks.IsBlocked(context.TODO(), currentUserID, targetUserID) {
+		return ErrBlocked
+	}
 
 	// Update current user's follow list
 	currentUserUpdate := bson.M{
@@ -0,0 +1,25 @@
+package reactions
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureReactionIndexes lets a user leave each reaction on an entity only
+// once, even when two toggles race.
+func EnsureReactionIndexes(coll *mongo.Collection) error {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "entity_type", Value: 1},
+			{Key: "entity_id", Value: 1},
+			{Key: "userid", Value: 1},
+			{Key: "reaction", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := coll.Indexes().CreateOne(context.Background(), indexModel)
+	return err
+}
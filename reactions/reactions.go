@@ -0,0 +1,221 @@
+// Package reactions lets users react to farms, crops, recipes, comments and
+// chat messages, addressed by entityType/entityId like comments and reviews.
+package reactions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"naevis/db"
+	"naevis/models"
+	"naevis/utils"
+)
+
+const defaultReactionSet = "like,love,laugh,wow,sad,angry"
+
+// allowedReactions is read from REACTIONS (comma separated) and falls back
+// to defaultReactionSet. Order is preserved for clients rendering a picker.
+var allowedReactions = loadReactionSet(os.Getenv("REACTIONS"))
+
+func loadReactionSet(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		raw = defaultReactionSet
+	}
+	var set []string
+	seen := map[string]bool{}
+	for _, r := range strings.Split(raw, ",") {
+		r = strings.ToLower(strings.TrimSpace(r))
+		if r != "" && !seen[r] {
+			seen[r] = true
+			set = append(set, r)
+		}
+	}
+	return set
+}
+
+func isAllowedReaction(reaction string) bool {
+	for _, r := range allowedReactions {
+		if r == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+// canSee reports whether the entity exists and userID may view it. Chat
+// messages are only visible to participants of their chat; held or hidden
+// recipes and comments are not visible at all.
+func canSee(ctx context.Context, entityType, entityID, userID string) bool {
+	oid, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return false
+	}
+
+	var coll *mongo.Collection
+	filter := bson.M{"_id": oid}
+	switch entityType {
+	case "farm":
+		coll, filter["deletedAt"] = db.FarmsCollection, nil
+	case "crop":
+		coll, filter["deletedAt"] = db.CropsCollection, nil
+	case "recipe":
+		coll, filter["hidden"] = db.RecipeCollection, bson.M{"$ne": true}
+	case "comment":
+		coll, filter["hidden"] = db.CommentsCollection, bson.M{"$ne": true}
+	case "message":
+		if userID == "" {
+			return false
+		}
+		var msg struct {
+			ChatID primitive.ObjectID `bson:"chatId"`
+		}
+		if err := db.MessagesCollection.FindOne(ctx, filter).Decode(&msg); err != nil {
+			return false
+		}
+		coll, filter = db.ChatsCollection, bson.M{"_id": msg.ChatID, "participants": userID}
+	default:
+		return false
+	}
+
+	n, err := coll.CountDocuments(ctx, filter)
+	return err == nil && n > 0
+}
+
+// summary returns per-reaction counts for an entity and the reactions
+// userID has left on it.
+func summary(ctx context.Context, entityType, entityID, userID string) (map[string]int, []string, error) {
+	cursor, err := db.ReactionsCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"entity_type": entityType, "entity_id": entityID}}},
+		{{Key: "$group", Value: bson.M{"_id": "$reaction", "count": bson.M{"$sum": 1}}}},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	counts := map[string]int{}
+	for cursor.Next(ctx) {
+		var row struct {
+			Reaction string `bson:"_id"`
+			Count    int    `bson:"count"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, nil, err
+		}
+		counts[row.Reaction] = row.Count
+	}
+
+	mine := []string{}
+	if userID != "" {
+		var own []models.Reaction
+		c, err := db.ReactionsCollection.Find(ctx, bson.M{"entity_type": entityType, "entity_id": entityID, "userid": userID})
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := c.All(ctx, &own); err != nil {
+			return nil, nil, err
+		}
+		for _, r := range own {
+			mine = append(mine, r.Reaction)
+		}
+	}
+	return counts, mine, nil
+}
+
+// GET /api/v1/reactions
+//
+// Lists the reactions users may pick from.
+func GetReactionSet(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"reactions": allowedReactions})
+}
+
+// GET /api/v1/reactions/:entitytype/:entityid
+func GetReactions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	entityType, entityID := ps.ByName("entitytype"), ps.ByName("entityid")
+	userID := utils.GetUserIDFromRequest(r)
+
+	if !canSee(r.Context(), entityType, entityID, userID) {
+		http.Error(w, "Entity not found", http.StatusNotFound)
+		return
+	}
+
+	counts, mine, err := summary(r.Context(), entityType, entityID, userID)
+	if err != nil {
+		http.Error(w, "Failed to load reactions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"counts": counts, "mine": mine})
+}
+
+// POST /api/v1/reactions/:entitytype/:entityid
+//
+// Body: { "reaction": "like" }. Toggles the caller's reaction: sending the
+// same reaction twice removes it.
+func ToggleReaction(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	entityType, entityID := ps.ByName("entitytype"), ps.ByName("entityid")
+	userID := utils.GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body struct {
+		Reaction string `json:"reaction"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+	body.Reaction = strings.ToLower(strings.TrimSpace(body.Reaction))
+	if !isAllowedReaction(body.Reaction) {
+		http.Error(w, "Unsupported reaction", http.StatusBadRequest)
+		return
+	}
+
+	if !canSee(r.Context(), entityType, entityID, userID) {
+		http.Error(w, "Entity not found", http.StatusNotFound)
+		return
+	}
+
+	filter := bson.M{"entity_type": entityType, "entity_id": entityID, "userid": userID, "reaction": body.Reaction}
+	res, err := db.ReactionsCollection.DeleteOne(r.Context(), filter)
+	if err != nil {
+		http.Error(w, "Failed to update reaction", http.StatusInternalServerError)
+		return
+	}
+
+	reacted := false
+	if res.DeletedCount == 0 {
+		reaction := models.Reaction{
+			EntityType: entityType,
+			EntityID:   entityID,
+			UserID:     userID,
+			Reaction:   body.Reaction,
+			CreatedAt:  time.Now(),
+		}
+		// A duplicate means a concurrent toggle already added it
+		if _, err := db.ReactionsCollection.InsertOne(r.Context(), reaction); err != nil && !mongo.IsDuplicateKeyError(err) {
+			http.Error(w, "Failed to update reaction", http.StatusInternalServerError)
+			return
+		}
+		reacted = true
+	}
+
+	counts, mine, err := summary(r.Context(), entityType, entityID, userID)
+	if err != nil {
+		http.Error(w, "Failed to load reactions", http.StatusInternalServerError)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, map[string]any{"reacted": reacted, "counts": counts, "mine": mine})
+}
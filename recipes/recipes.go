@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"log"
+	"naevis/contentfilter"
 	"naevis/db"
 	"naevis/models"
 	"naevis/utils"
@@ -20,10 +22,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// recipeText is the free text of a recipe the content filter looks at.
+func recipeText(title, description string, steps []string) string {
+	return title + "\n" + description + "\n" + strings.Join(steps, "\n")
+}
+
 // Get all recipes
 func GetRecipes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	ctx := context.TODO()
-	query := bson.M{}
+	query := bson.M{"hidden": bson.M{"$ne": true}}
 
 	// --- Parse query params ---
 	search := r.URL.Query().Get("search")
@@ -62,6 +69,8 @@ func GetRecipes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		sort = bson.D{{Key: "createdAt", Value: 1}}
 	case "popular":
 		sort = bson.D{{Key: "views", Value: -1}}
+	case "rating":
+		sort = bson.D{{Key: "avgRating", Value: -1}, {Key: "reviewCount", Value: -1}}
 	}
 
 	// --- Execute query ---
@@ -110,7 +119,7 @@ func GetRecipes(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 func GetRecipe(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	id, _ := primitive.ObjectIDFromHex(ps.ByName("id"))
 	var recipe models.Recipe
-	err := db.RecipeCollection.FindOne(context.TODO(), bson.M{"_id": id}).Decode(&recipe)
+	err := db.RecipeCollection.FindOne(context.TODO(), bson.M{"_id": id, "hidden": bson.M{"$ne": true}}).Decode(&recipe)
 	if err != nil {
 		http.Error(w, "Recipe not found", http.StatusNotFound)
 		return
@@ -140,6 +149,12 @@ func CreateRecipe(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	steps := splitLines(r.FormValue("steps"))
 	difficulty := r.FormValue("difficulty")
 
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{UserID: userID, EntityType: "recipe", Text: recipeText(title, description, steps)})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "Recipe rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+
 	var servings int
 	if val := r.FormValue("servings"); val != "" {
 		if parsed, err := strconv.Atoi(val); err == nil {
@@ -224,6 +239,7 @@ func CreateRecipe(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		Servings:    servings,
 		CreatedAt:   time.Now().Unix(),
 		Views:       0,
+		Hidden:      verdict.Verdict == contentfilter.Hold,
 	}
 
 	result, err := db.RecipeCollection.InsertOne(context.TODO(), recipe)
@@ -231,6 +247,12 @@ func CreateRecipe(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 		http.Error(w, "DB insert failed", http.StatusInternalServerError)
 		return
 	}
+	if recipe.Hidden {
+		recipeID := result.InsertedID.(primitive.ObjectID).Hex()
+		if err := contentfilter.HoldForReview(r.Context(), "recipe", recipeID, userID, verdict, true); err != nil {
+			log.Printf("Failed to queue held recipe %s: %v", recipeID, err)
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
@@ -390,6 +412,20 @@ func UpdateRecipe(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		// add additional fields as needed
 	}
 
+	userID := utils.GetUserIDFromRequest(r)
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{
+		UserID:     userID,
+		EntityType: "recipe",
+		Text:       recipeText(r.FormValue("title"), r.FormValue("description"), splitLines(r.FormValue("steps"))),
+	})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "Recipe rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+	if verdict.Verdict == contentfilter.Hold {
+		updates["hidden"] = true
+	}
+
 	// Handle new image uploads
 	files := r.MultipartForm.File["imageUrls"]
 	var imagePaths []string
@@ -428,6 +464,13 @@ func UpdateRecipe(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if verdict.Verdict == contentfilter.Hold {
+		if err := contentfilter.HoldForReview(r.Context(), "recipe", id.Hex(), userID, verdict, true); err != nil {
+			log.Printf("Failed to queue held recipe %s: %v", id.Hex(), err)
+		}
+		w.Write([]byte(`{"status":"held"}`))
+		return
+	}
 	w.Write([]byte(`{"status":"updated"}`))
 }
 
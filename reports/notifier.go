@@ -0,0 +1,181 @@
+package reports
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/mailer"
+	"naevis/models"
+	"naevis/notifications"
+	"naevis/settings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	notifyInterval    = time.Minute
+	notifyLease       = 5 * time.Minute // how long a claimed report is left alone
+	notifyBaseBackoff = time.Minute
+	notifyMaxBackoff  = 6 * time.Hour
+	maxNotifyAttempts = 10
+)
+
+// notifyReset clears the notifier's delivery state; apply it with
+// "notified": false whenever a report is resolved.
+var notifyReset = bson.M{"notifiedInApp": "", "notifyAttempts": "", "nextNotifyAt": ""}
+
+// NotifyReporters periodically tells reporters how their resolved reports
+// were handled, in the app and by email if they opted in. Failed deliveries
+// are retried with exponential backoff.
+func NotifyReporters() {
+	ticker := time.NewTicker(notifyInterval)
+	for range ticker.C {
+		notifyPendingReporters()
+	}
+}
+
+func notifyPendingReporters() {
+	ctx, cancel := context.WithTimeout(context.Background(), notifyInterval)
+	defer cancel()
+
+	for ctx.Err() == nil {
+		report, err := claimReport(ctx)
+		if err == mongo.ErrNoDocuments {
+			return
+		} else if err != nil {
+			log.Println("Report notifier claim error:", err)
+			return
+		}
+		if err := notifyReporter(ctx, report); err != nil {
+			log.Printf("Report notifier: %s attempt %d failed: %v", report.ID.Hex(), report.NotifyAttempts+1, err)
+			retryLater(ctx, report)
+		}
+	}
+}
+
+// claimReport picks the next report due for notification and pushes its
+// next attempt out by notifyLease, so parallel workers don't both send it.
+func claimReport(ctx context.Context) (models.Report, error) {
+	now := time.Now().UTC()
+	var report models.Report
+	err := db.ReportsCollection.FindOneAndUpdate(ctx,
+		bson.M{
+			"status":         "resolved",
+			"notified":       false,
+			"notifyAttempts": bson.M{"$not": bson.M{"$gte": maxNotifyAttempts}},
+			"$or": []bson.M{
+				{"nextNotifyAt": bson.M{"$exists": false}},
+				{"nextNotifyAt": bson.M{"$lte": now}},
+			},
+		},
+		bson.M{"$set": bson.M{"nextNotifyAt": now.Add(notifyLease)}},
+		options.FindOneAndUpdate().SetSort(bson.M{"updatedAt": 1}),
+	).Decode(&report)
+	return report, err
+}
+
+// notifyReporter sends the in-app notification once, then the email if the
+// reporter wants one, and marks the report notified. Reports filed by the
+// content filter have no one to tell.
+func notifyReporter(ctx context.Context, report models.Report) error {
+	if report.ReportedBy == models.SystemReporter {
+		return markNotified(ctx, report)
+	}
+	title, body := reportOutcome(report)
+
+	if !report.NotifiedInApp {
+		err := notifications.Send(ctx, report.ReportedBy, "report-resolved", title, body, report.TargetType, report.TargetID)
+		if err != nil {
+			return err
+		}
+		_, err = db.ReportsCollection.UpdateByID(ctx, report.ID, bson.M{"$set": bson.M{"notifiedInApp": true}})
+		if err != nil {
+			return err
+		}
+	}
+
+	if mailer.Configured() && settings.EmailNotificationsEnabled(ctx, report.ReportedBy) {
+		var user struct {
+			Email string `bson:"email"`
+		}
+		err := db.UserCollection.FindOne(ctx, bson.M{"userid": report.ReportedBy}).Decode(&user)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		if user.Email != "" {
+			if err := mailer.Send(user.Email, title, body); err != nil {
+				return err
+			}
+		}
+	}
+
+	return markNotified(ctx, report)
+}
+
+func markNotified(ctx context.Context, report models.Report) error {
+	_, err := db.ReportsCollection.UpdateByID(ctx, report.ID, bson.M{
+		"$set":   bson.M{"notified": true},
+		"$unset": bson.M{"nextNotifyAt": "", "notifyAttempts": ""},
+	})
+	return err
+}
+
+func retryLater(ctx context.Context, report models.Report) {
+	attempts := report.NotifyAttempts + 1
+	backoff := notifyBaseBackoff << (attempts - 1)
+	if backoff > notifyMaxBackoff || backoff <= 0 {
+		backoff = notifyMaxBackoff
+	}
+	_, err := db.ReportsCollection.UpdateByID(ctx, report.ID, bson.M{"$set": bson.M{
+		"notifyAttempts": attempts,
+		"nextNotifyAt":   time.Now().UTC().Add(backoff),
+	}})
+	if err != nil {
+		log.Printf("Report notifier: failed to schedule retry for %s: %v", report.ID.Hex(), err)
+	}
+	if attempts >= maxNotifyAttempts {
+		log.Printf("Report notifier: giving up on %s after %d attempts", report.ID.Hex(), attempts)
+	}
+}
+
+// reportOutcome describes what moderators did about the reported item,
+// based on the actions still in force.
+func reportOutcome(report models.Report) (string, string) {
+	var outcomes []string
+	for _, a := range report.Actions {
+		if a.RevertedAt != nil {
+			continue
+		}
+		switch a.Action {
+		case "hide", "delete":
+			outcomes = append(outcomes, "the "+report.TargetType+" was removed")
+		case "warn":
+			outcomes = append(outcomes, "the user responsible was warned")
+		case "suspend":
+			outcomes = append(outcomes, "the user responsible was suspended")
+		}
+	}
+
+	title := "Your report has been reviewed"
+	if len(outcomes) == 0 {
+		return title, "Thanks for your report about a " + report.TargetType + ". A moderator reviewed it and decided no action was needed."
+	}
+	return title, "Thanks for your report about a " + report.TargetType + ". After review, " + strings.Join(dedupe(outcomes), " and ") + "."
+}
+
+func dedupe(items []string) []string {
+	seen := map[string]bool{}
+	out := items[:0]
+	for _, item := range items {
+		if !seen[item] {
+			seen[item] = true
+			out = append(out, item)
+		}
+	}
+	return out
+}
@@ -9,6 +9,7 @@ import (
 
 	"naevis/db"
 	"naevis/models"
+	"naevis/utils"
 
 	"github.com/julienschmidt/httprouter"
 	"go.mongodb.org/mongo-driver/bson"
@@ -186,6 +187,9 @@ func UpdateReport(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 	payload.Status = stringTrim(payload.Status)
 	payload.ReviewedBy = stringTrim(payload.ReviewedBy)
 	payload.ReviewNotes = stringTrim(payload.ReviewNotes)
+	if payload.ReviewedBy == "" {
+		payload.ReviewedBy = utils.GetUserIDFromRequest(r)
+	}
 
 	// Validate that status is provided
 	if payload.Status == "" {
@@ -212,6 +216,9 @@ func UpdateReport(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	filter := bson.M{"_id": objID}
 	update := bson.M{"$set": updateFields}
+	if payload.Status == "resolved" {
+		update["$unset"] = notifyReset
+	}
 
 	resUpdate, err := db.ReportsCollection.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
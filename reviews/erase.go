@@ -0,0 +1,80 @@
+package reviews
+
+import (
+	"context"
+
+	"naevis/db"
+	"naevis/mq"
+	"naevis/structs"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EraseUser removes everything userID wrote in reviews: their reviews (with
+// the votes on them and their share of the rating aggregates), their votes
+// on other reviews and their owner responses. It returns how many reviews
+// were deleted.
+func EraseUser(ctx context.Context, userID string) (int, error) {
+	cursor, err := db.ReviewsCollection.Find(ctx, bson.M{"userid": userID})
+	if err != nil {
+		return 0, err
+	}
+	var own []structs.Review
+	if err := cursor.All(ctx, &own); err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, review := range own {
+		err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+			res, err := db.ReviewsCollection.DeleteOne(sc, bson.M{"reviewid": review.ReviewID})
+			if err != nil || res.DeletedCount == 0 {
+				return err
+			}
+			if _, err := db.ReviewVotesCollection.DeleteMany(sc, bson.M{"reviewid": review.ReviewID}); err != nil {
+				return err
+			}
+			if review.Hidden {
+				return nil
+			}
+			return applyRatingChange(sc, review.EntityType, review.EntityID, review.Rating, 0)
+		})
+		if err != nil {
+			return deleted, err
+		}
+		removeReviewImages(review.Attachments)
+		deleted++
+
+		m := mq.Index{EntityType: "review", EntityId: review.ReviewID, Method: "DELETE", ItemId: review.EntityID, ItemType: review.EntityType}
+		go mq.Emit("review-deleted", m)
+	}
+
+	cursor, err = db.ReviewVotesCollection.Find(ctx, bson.M{"userid": userID})
+	if err != nil {
+		return deleted, err
+	}
+	var votes []structs.ReviewVote
+	if err := cursor.All(ctx, &votes); err != nil {
+		return deleted, err
+	}
+	for _, vote := range votes {
+		err := withTransaction(ctx, func(sc mongo.SessionContext) error {
+			res, err := db.ReviewVotesCollection.DeleteOne(sc, bson.M{"reviewid": vote.ReviewID, "userid": userID})
+			if err != nil || res.DeletedCount == 0 {
+				return err
+			}
+			_, err = db.ReviewsCollection.UpdateOne(sc, bson.M{"reviewid": vote.ReviewID}, bson.M{"$inc": bson.M{voteCounter(vote.Vote): -1}})
+			return err
+		})
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	_, err = db.ReviewsCollection.UpdateMany(ctx,
+		bson.M{"response.userid": userID},
+		bson.M{"$unset": bson.M{"response": ""}},
+	)
+	return deleted, err
+}
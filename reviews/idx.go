@@ -0,0 +1,24 @@
+package reviews
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureReviewVoteIndexes keeps one vote per user on a review. With it in
+// place MongoDB retries a VoteReview upsert that loses a race as an update,
+// so the counters are only moved once.
+func EnsureReviewVoteIndexes(coll *mongo.Collection) error {
+	indexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "reviewid", Value: 1},
+			{Key: "userid", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	_, err := coll.Indexes().CreateOne(context.Background(), indexModel)
+	return err
+}
@@ -0,0 +1,137 @@
+package reviews
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"naevis/structs"
+	"naevis/utils"
+)
+
+const (
+	maxReviewImages = 5
+	maxReviewUpload = 20 << 20 // 20MB across all images
+	reviewPicDir    = "./static/reviewpic"
+	reviewThumbSize = 300
+)
+
+var errTooManyImages = fmt.Errorf("a review can have at most %d images", maxReviewImages)
+
+func isMultipart(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+}
+
+// decodeReviewForm reads a new review from JSON or multipart form fields.
+// Attachments are never taken from the client; they come from uploads only.
+func decodeReviewForm(r *http.Request) (structs.Review, error) {
+	var review structs.Review
+	if isMultipart(r) {
+		if err := r.ParseMultipartForm(maxReviewUpload); err != nil {
+			return review, err
+		}
+		review.Rating, _ = strconv.Atoi(r.FormValue("rating"))
+		review.Comment = r.FormValue("comment")
+		review.Content = r.FormValue("content")
+	} else if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		return review, err
+	}
+	review.Attachments = nil
+	return review, nil
+}
+
+// decodeReviewUpdate reads the fields of an edit from JSON or multipart form
+// fields. Multipart rating values are passed on as float64 to match JSON.
+func decodeReviewUpdate(r *http.Request) (map[string]any, error) {
+	updatedFields := map[string]any{}
+	if !isMultipart(r) {
+		err := json.NewDecoder(r.Body).Decode(&updatedFields)
+		return updatedFields, err
+	}
+
+	if err := r.ParseMultipartForm(maxReviewUpload); err != nil {
+		return nil, err
+	}
+	if v := r.FormValue("rating"); v != "" {
+		rating, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, err
+		}
+		updatedFields["rating"] = rating
+	}
+	for _, field := range []string{"comment", "content"} {
+		if v := r.FormValue(field); v != "" {
+			updatedFields[field] = v
+		}
+	}
+	return updatedFields, nil
+}
+
+// uploadedImageCount returns how many files were sent under "images".
+func uploadedImageCount(r *http.Request) int {
+	if r.MultipartForm == nil {
+		return 0
+	}
+	return len(r.MultipartForm.File["images"])
+}
+
+// saveReviewImages stores the uploaded "images" files under reviewPicDir and
+// writes a thumbnail for each into reviewPicDir/thumb. It returns the stored
+// file names; on failure anything already written is removed.
+func saveReviewImages(r *http.Request) ([]string, error) {
+	if uploadedImageCount(r) == 0 {
+		return nil, nil
+	}
+	if err := os.MkdirAll(reviewPicDir, 0755); err != nil {
+		return nil, err
+	}
+
+	var saved []string
+	for _, header := range r.MultipartForm.File["images"] {
+		if !utils.SupportedImageTypes[header.Header.Get("Content-Type")] {
+			removeReviewImages(saved)
+			return nil, errors.New("unsupported image type")
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			removeReviewImages(saved)
+			return nil, err
+		}
+		name, err := utils.SaveFile(file, header, reviewPicDir)
+		file.Close()
+		if err != nil {
+			removeReviewImages(saved)
+			return nil, err
+		}
+		saved = append(saved, name)
+
+		ext := filepath.Ext(name)
+		if err := utils.CreateThumb(strings.TrimSuffix(name, ext), reviewPicDir, ext, reviewThumbSize, reviewThumbSize); err != nil {
+			removeReviewImages(saved)
+			return nil, err
+		}
+	}
+	return saved, nil
+}
+
+// removeReviewImages deletes stored review images and their thumbnails.
+func removeReviewImages(names []string) {
+	for _, name := range names {
+		name = filepath.Base(name)
+		for _, path := range []string{
+			filepath.Join(reviewPicDir, name),
+			filepath.Join(reviewPicDir, "thumb", name),
+		} {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				log.Printf("Error removing review image %s: %v", path, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package reviews
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"naevis/db"
+	"naevis/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ratingTarget returns the collection whose documents carry denormalized
+// avgRating/reviewCount fields for entityType, or nil when the type only has
+// its aggregate in the ratings collection.
+func ratingTarget(entityType string) *mongo.Collection {
+	switch entityType {
+	case "farm":
+		return db.FarmsCollection
+	case "crop":
+		return db.CropsCollection
+	case "recipe":
+		return db.RecipeCollection
+	}
+	return nil
+}
+
+// noTransactions is set once the server has refused a transaction, which a
+// standalone mongod does; only replica sets and sharded clusters have them.
+var noTransactions atomic.Bool
+
+// withTransaction runs fn inside a MongoDB transaction so a review and the
+// aggregate it feeds are always written together. On a standalone server,
+// where transactions are not available, fn runs without one: the writes
+// are no longer atomic, and a crash between them can leave an aggregate off
+// by one review until it is recomputed.
+func withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := db.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	if !noTransactions.Load() {
+		_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+			return nil, fn(sc)
+		})
+		if !transactionsUnsupported(err) {
+			return err
+		}
+		noTransactions.Store(true)
+		log.Println("MongoDB does not support transactions (not a replica set); review writes will not be atomic")
+	}
+	return fn(mongo.NewSessionContext(ctx, session))
+}
+
+// transactionsUnsupported reports whether err is the server refusing a
+// transaction outright (IllegalOperation), in which case nothing was written.
+func transactionsUnsupported(err error) bool {
+	var se mongo.ServerError
+	return errors.As(err, &se) && se.HasErrorCode(20)
+}
+
+// AdjustRating lets other packages move a review's contribution, e.g.
+// moderation taking a review down (n, 0) or restoring it (0, n).
+func AdjustRating(ctx context.Context, entityType, entityID string, oldRating, newRating int) error {
+	return applyRatingChange(ctx, entityType, entityID, oldRating, newRating)
+}
+
+// applyRatingChange moves one review's contribution from oldRating to
+// newRating. Zero means "no review", so (0, n) is an add and (n, 0) a delete.
+func applyRatingChange(ctx context.Context, entityType, entityID string, oldRating, newRating int) error {
+	if oldRating == newRating {
+		return nil
+	}
+
+	inc := bson.M{}
+	count, sum := 0, 0
+	if oldRating > 0 {
+		count--
+		sum -= oldRating
+		inc["histogram."+strconv.Itoa(oldRating)] = -1
+	}
+	if newRating > 0 {
+		count++
+		sum += newRating
+		inc["histogram."+strconv.Itoa(newRating)] = 1
+	}
+	inc["count"] = count
+	inc["sum"] = sum
+
+	var summary models.RatingSummary
+	err := db.RatingsCollection.FindOneAndUpdate(ctx,
+		bson.M{"entity_type": entityType, "entity_id": entityID},
+		bson.M{"$inc": inc, "$set": bson.M{"updatedAt": time.Now()}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&summary)
+	if err != nil {
+		return err
+	}
+	if summary.Count < 0 {
+		return fmt.Errorf("rating count for %s %s went negative", entityType, entityID)
+	}
+
+	summary.Average = averageOf(summary.Sum, summary.Count)
+	if _, err := db.RatingsCollection.UpdateOne(ctx,
+		bson.M{"entity_type": entityType, "entity_id": entityID},
+		bson.M{"$set": bson.M{"average": summary.Average}},
+	); err != nil {
+		return err
+	}
+
+	return mirrorRating(ctx, summary)
+}
+
+// mirrorRating copies the aggregate onto the reviewed entity so listings can
+// sort by it without a join.
+func mirrorRating(ctx context.Context, summary models.RatingSummary) error {
+	coll := ratingTarget(summary.EntityType)
+	if coll == nil {
+		return nil
+	}
+	oid, err := primitive.ObjectIDFromHex(summary.EntityID)
+	if err != nil {
+		return nil
+	}
+	_, err = coll.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{
+		"avgRating":   summary.Average,
+		"reviewCount": summary.Count,
+	}})
+	return err
+}
+
+func averageOf(sum, count int) float64 {
+	if count <= 0 {
+		return 0
+	}
+	return math.Round(float64(sum)/float64(count)*100) / 100
+}
+
+// getRatingSummary returns the aggregate for an entity, zero-valued when it
+// has no reviews yet.
+func getRatingSummary(ctx context.Context, entityType, entityID string) models.RatingSummary {
+	summary := models.RatingSummary{EntityType: entityType, EntityID: entityID}
+	_ = db.RatingsCollection.FindOne(ctx, bson.M{"entity_type": entityType, "entity_id": entityID}).Decode(&summary)
+	if summary.Histogram == nil {
+		summary.Histogram = map[string]int{}
+	}
+	for star := 1; star <= 5; star++ {
+		if _, ok := summary.Histogram[strconv.Itoa(star)]; !ok {
+			summary.Histogram[strconv.Itoa(star)] = 0
+		}
+	}
+	return summary
+}
+
+// RecomputeRatings rebuilds every rating aggregate from the reviews
+// collection and re-mirrors it onto the reviewed entities. Aggregates for
+// entities that no longer have reviews are reset. It returns the number of
+// entities with reviews.
+func RecomputeRatings(ctx context.Context) (int, error) {
+	group := bson.M{
+		"_id":   bson.M{"entity_type": "$entity_type", "entity_id": "$entity_id"},
+		"count": bson.M{"$sum": 1},
+		"sum":   bson.M{"$sum": "$rating"},
+	}
+	for star := 1; star <= 5; star++ {
+		group["h"+strconv.Itoa(star)] = bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$rating", star}}, 1, 0}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"rating": bson.M{"$gte": 1, "$lte": 5}, "hidden": bson.M{"$ne": true}}}},
+		{{Key: "$group", Value: group}},
+	}
+
+	cursor, err := db.ReviewsCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	type key struct{ entityType, entityID string }
+	seen := map[key]bool{}
+	now := time.Now()
+
+	for cursor.Next(ctx) {
+		var row struct {
+			ID struct {
+				EntityType string `bson:"entity_type"`
+				EntityID   string `bson:"entity_id"`
+			} `bson:"_id"`
+			Count int `bson:"count"`
+			Sum   int `bson:"sum"`
+			H1    int `bson:"h1"`
+			H2    int `bson:"h2"`
+			H3    int `bson:"h3"`
+			H4    int `bson:"h4"`
+			H5    int `bson:"h5"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return len(seen), err
+		}
+
+		summary := models.RatingSummary{
+			EntityType: row.ID.EntityType,
+			EntityID:   row.ID.EntityID,
+			Count:      row.Count,
+			Sum:        row.Sum,
+			Average:    averageOf(row.Sum, row.Count),
+			Histogram:  map[string]int{"1": row.H1, "2": row.H2, "3": row.H3, "4": row.H4, "5": row.H5},
+			UpdatedAt:  now,
+		}
+		_, err := db.RatingsCollection.ReplaceOne(ctx,
+			bson.M{"entity_type": summary.EntityType, "entity_id": summary.EntityID},
+			summary,
+			options.Replace().SetUpsert(true),
+		)
+		if err != nil {
+			return len(seen), err
+		}
+		if err := mirrorRating(ctx, summary); err != nil {
+			return len(seen), err
+		}
+		seen[key{summary.EntityType, summary.EntityID}] = true
+	}
+	if err := cursor.Err(); err != nil {
+		return len(seen), err
+	}
+
+	// Drop aggregates whose reviews are all gone.
+	stale, err := db.RatingsCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return len(seen), err
+	}
+	defer stale.Close(ctx)
+	for stale.Next(ctx) {
+		var summary models.RatingSummary
+		if err := stale.Decode(&summary); err != nil {
+			return len(seen), err
+		}
+		if seen[key{summary.EntityType, summary.EntityID}] {
+			continue
+		}
+		if _, err := db.RatingsCollection.DeleteOne(ctx, bson.M{"entity_type": summary.EntityType, "entity_id": summary.EntityID}); err != nil {
+			return len(seen), err
+		}
+		if err := mirrorRating(ctx, models.RatingSummary{EntityType: summary.EntityType, EntityID: summary.EntityID}); err != nil {
+			return len(seen), err
+		}
+	}
+
+	return len(seen), stale.Err()
+}
@@ -0,0 +1,214 @@
+package reviews
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"naevis/db"
+	"naevis/globals"
+	"naevis/middleware"
+	"naevis/notifications"
+	"naevis/owners"
+	"naevis/structs"
+
+	"github.com/julienschmidt/httprouter"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const maxResponseLength = 2000
+
+// PUT /api/reviews/:entityType/:entityId/:reviewId/response
+//
+// Creates or replaces the owner's single response to a review.
+func RespondToReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userId, _ := r.Context().Value(globals.UserIDKey).(string)
+	reviewId := ps.ByName("reviewId")
+
+	var review structs.Review
+	err := db.ReviewsCollection.FindOne(context.TODO(), bson.M{"reviewid": reviewId}).Decode(&review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Review not found: %v", err), http.StatusNotFound)
+		return
+	}
+
+	owner, err := owners.EntityOwner(r.Context(), review.EntityType, review.EntityID)
+	if err != nil || owner == "" || owner != userId {
+		http.Error(w, "Only the owner can respond to this review", http.StatusForbidden)
+		return
+	}
+
+	var body struct {
+		Body string `json:"body"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid response data", http.StatusBadRequest)
+		return
+	}
+	body.Body = strings.TrimSpace(body.Body)
+	if body.Body == "" || len(body.Body) > maxResponseLength {
+		http.Error(w, "Response must be between 1 and 2000 characters", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	response := structs.ReviewResponse{UserID: userId, Body: body.Body, CreatedAt: now, UpdatedAt: now}
+	if review.Response != nil {
+		response.CreatedAt = review.Response.CreatedAt
+	}
+
+	_, err = db.ReviewsCollection.UpdateOne(context.TODO(),
+		bson.M{"reviewid": reviewId},
+		bson.M{"$set": bson.M{"response": response}},
+	)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to save response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	if review.Response == nil {
+		go notifications.Notify(review.UserID, "review-response", "The owner responded to your review", response.Body, review.EntityType, review.EntityID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DELETE /api/reviews/:entityType/:entityId/:reviewId/response
+func DeleteReviewResponse(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userId, _ := r.Context().Value(globals.UserIDKey).(string)
+	reviewId := ps.ByName("reviewId")
+
+	var review structs.Review
+	err := db.ReviewsCollection.FindOne(context.TODO(), bson.M{"reviewid": reviewId}).Decode(&review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Review not found: %v", err), http.StatusNotFound)
+		return
+	}
+	if review.Response == nil {
+		http.Error(w, "Review has no response", http.StatusNotFound)
+		return
+	}
+	if review.Response.UserID != userId && !middleware.IsModerator(r.Context(), userId) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	_, err = db.ReviewsCollection.UpdateOne(context.TODO(),
+		bson.M{"reviewid": reviewId},
+		bson.M{"$unset": bson.M{"response": ""}},
+	)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete response: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// errVoteConflict is a vote upsert that lost a race with the same user's
+// other request on the unique reviewid+userid index.
+var errVoteConflict = errors.New("concurrent vote")
+
+// PUT /api/reviews/:entityType/:entityId/:reviewId/vote
+//
+// Body: { "vote": "helpful" | "unhelpful" }. Voting again replaces the
+// user's earlier vote.
+func VoteReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userId, _ := r.Context().Value(globals.UserIDKey).(string)
+	reviewId := ps.ByName("reviewId")
+
+	var body struct {
+		Vote string `json:"vote"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || (body.Vote != "helpful" && body.Vote != "unhelpful") {
+		http.Error(w, "Vote must be helpful or unhelpful", http.StatusBadRequest)
+		return
+	}
+
+	var review structs.Review
+	err := db.ReviewsCollection.FindOne(context.TODO(), bson.M{"reviewid": reviewId}).Decode(&review)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Review not found: %v", err), http.StatusNotFound)
+		return
+	}
+	if review.UserID == userId {
+		http.Error(w, "You cannot vote on your own review", http.StatusForbidden)
+		return
+	}
+
+	err = withTransaction(r.Context(), func(sc mongo.SessionContext) error {
+		var previous structs.ReviewVote
+		err := db.ReviewVotesCollection.FindOneAndUpdate(sc,
+			bson.M{"reviewid": reviewId, "userid": userId},
+			bson.M{
+				"$set":         bson.M{"vote": body.Vote},
+				"$setOnInsert": bson.M{"createdAt": time.Now()},
+			},
+			options.FindOneAndUpdate().SetUpsert(true),
+		).Decode(&previous)
+		if mongo.IsDuplicateKeyError(err) {
+			return errVoteConflict
+		}
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+		if previous.Vote == body.Vote {
+			return nil
+		}
+
+		inc := bson.M{voteCounter(body.Vote): 1}
+		if previous.Vote != "" {
+			inc[voteCounter(previous.Vote)] = -1
+		}
+		_, err = db.ReviewsCollection.UpdateOne(sc, bson.M{"reviewid": reviewId}, bson.M{"$inc": inc})
+		return err
+	})
+	if err == errVoteConflict {
+		http.Error(w, "Another vote is being recorded, try again", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to record vote: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// DELETE /api/reviews/:entityType/:entityId/:reviewId/vote
+func UnvoteReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userId, _ := r.Context().Value(globals.UserIDKey).(string)
+	reviewId := ps.ByName("reviewId")
+
+	err := withTransaction(r.Context(), func(sc mongo.SessionContext) error {
+		var previous structs.ReviewVote
+		err := db.ReviewVotesCollection.FindOneAndDelete(sc, bson.M{"reviewid": reviewId, "userid": userId}).Decode(&previous)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		_, err = db.ReviewsCollection.UpdateOne(sc, bson.M{"reviewid": reviewId}, bson.M{"$inc": bson.M{voteCounter(previous.Vote): -1}})
+		return err
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to remove vote: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func voteCounter(vote string) string {
+	if vote == "helpful" {
+		return "helpful_count"
+	}
+	return "unhelpful_count"
+}
@@ -5,17 +5,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"naevis/blocks"
+	"naevis/contentfilter"
 	"naevis/db"
 	"naevis/globals"
+	"naevis/middleware"
 	"naevis/mq"
 	"naevis/structs"
 	"naevis/utils"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -29,6 +35,12 @@ func GetReviews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	skip, limit, filters, sort := parseQueryParams(r)
 	filters["entity_type"] = entityType
 	filters["entity_id"] = entityId
+	filters["hidden"] = bson.M{"$ne": true}
+	if userId, _ := r.Context().Value(globals.UserIDKey).(string); userId != "" {
+		if blocked := blocks.BlockedIDs(r.Context(), userId); len(blocked) > 0 {
+			filters["userid"] = bson.M{"$nin": blocked}
+		}
+	}
 
 	// Create options for the Find query
 	findOptions := options.Find().
@@ -58,6 +70,7 @@ func GetReviews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		"status":  http.StatusOK,
 		"ok":      true,
 		"reviews": reviews,
+		"summary": getRatingSummary(r.Context(), entityType, entityId),
 	}
 	log.Println("gets reviews : ", reviews)
 	json.NewEncoder(w).Encode(response)
@@ -67,8 +80,15 @@ func GetReviews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 func GetReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	reviewId := ps.ByName("reviewId")
 
+	filter := bson.M{"reviewid": reviewId, "hidden": bson.M{"$ne": true}}
+	if userId, _ := r.Context().Value(globals.UserIDKey).(string); userId != "" {
+		if blocked := blocks.BlockedIDs(r.Context(), userId); len(blocked) > 0 {
+			filter["userid"] = bson.M{"$nin": blocked}
+		}
+	}
+
 	var review structs.Review
-	err := db.ReviewsCollection.FindOne(context.TODO(), bson.M{"reviewid": reviewId}).Decode(&review)
+	err := db.ReviewsCollection.FindOne(context.TODO(), filter).Decode(&review)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Review not found: %v", err), http.StatusNotFound)
 		return
@@ -93,9 +113,9 @@ func AddReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	entityId := ps.ByName("entityId")
 
 	count, err := db.ReviewsCollection.CountDocuments(context.TODO(), bson.M{
-		"userId":     userId,
-		"entityType": entityType,
-		"entityId":   entityId,
+		"userid":      userId,
+		"entity_type": entityType,
+		"entity_id":   entityId,
 	})
 	if err != nil {
 		log.Printf("Error checking for existing review: %v", err)
@@ -108,24 +128,68 @@ func AddReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	var review structs.Review
-	if err := json.NewDecoder(r.Body).Decode(&review); err != nil || review.Rating < 1 || review.Rating > 5 || review.Comment == "" {
+	review, err := decodeReviewForm(r)
+	if err != nil || review.Rating < 1 || review.Rating > 5 || review.Comment == "" {
 		http.Error(w, "Invalid review data", http.StatusBadRequest)
 		return
 	}
+	if uploadedImageCount(r) > maxReviewImages {
+		http.Error(w, errTooManyImages.Error(), http.StatusBadRequest)
+		return
+	}
+
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{UserID: userId, EntityType: "review", Text: review.Comment + "\n" + review.Content})
+	if verdict.Verdict == contentfilter.Reject {
+		http.Error(w, "Review rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+
+	verified, err := isVerifiedPurchase(r.Context(), userId, entityType, entityId)
+	if err != nil {
+		log.Printf("Error checking verified purchase: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	if !verified && requiresVerifiedBuyer(r.Context(), entityType, entityId) {
+		http.Error(w, errVerifiedBuyersOnly.Error(), http.StatusForbidden)
+		return
+	}
 
 	review.ReviewID = utils.GenerateID(16)
 	review.UserID = userId
 	review.EntityType = entityType
 	review.EntityID = entityId
+	review.Verified = verified
+	review.Hidden = verdict.Verdict == contentfilter.Hold
 	// review.Date = time.Now().Format(time.RFC3339)
 	review.Date = time.Now()
 
-	inserted, err := db.ReviewsCollection.InsertOne(context.TODO(), review)
+	if review.Attachments, err = saveReviewImages(r); err != nil {
+		http.Error(w, "Failed to save images: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var inserted *mongo.InsertOneResult
+	err = withTransaction(r.Context(), func(sc mongo.SessionContext) error {
+		var err error
+		if inserted, err = db.ReviewsCollection.InsertOne(sc, review); err != nil {
+			return err
+		}
+		if review.Hidden {
+			return nil
+		}
+		return applyRatingChange(sc, entityType, entityId, 0, review.Rating)
+	})
 	if err != nil {
+		removeReviewImages(review.Attachments)
 		http.Error(w, "Failed to insert review: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if review.Hidden {
+		if err := contentfilter.HoldForReview(r.Context(), "review", review.ReviewID, userId, verdict, true); err != nil {
+			log.Printf("Failed to queue held review %s: %v", review.ReviewID, err)
+		}
+	}
 
 	m := mq.Index{EntityType: "review", EntityId: review.ReviewID, Method: "POST", ItemId: entityId, ItemType: entityType}
 	go mq.Emit("review-added", m)
@@ -148,26 +212,104 @@ func EditReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	if review.UserID != userId && !isAdmin(r.Context()) {
+	if review.UserID != userId && !middleware.IsModerator(r.Context(), userId) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
-	var updatedFields map[string]any
-	if err := json.NewDecoder(r.Body).Decode(&updatedFields); err != nil {
+	updatedFields, err := decodeReviewUpdate(r)
+	if err != nil {
 		http.Error(w, "Invalid update data", http.StatusBadRequest)
 		return
 	}
 
-	_, err = db.ReviewsCollection.UpdateOne(
-		context.TODO(),
-		bson.M{"reviewid": reviewId},
-		bson.M{"$set": updatedFields},
-	)
+	// Identity fields feed the rating aggregate; badges, votes, the owner response and attachments are server-managed.
+	for _, key := range []string{"_id", "reviewid", "userid", "entity_type", "entity_id", "verified_purchase", "helpful_count", "unhelpful_count", "response", "attachments", "hidden"} {
+		delete(updatedFields, key)
+	}
+
+	// Multipart edits may drop existing images (removeImages) and upload new ones (images).
+	var removed, added []string
+	if isMultipart(r) {
+		drop := map[string]bool{}
+		for _, name := range r.MultipartForm.Value["removeImages"] {
+			drop[name] = true
+		}
+		kept := []string{}
+		for _, name := range review.Attachments {
+			if drop[name] {
+				removed = append(removed, name)
+			} else {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept)+uploadedImageCount(r) > maxReviewImages {
+			http.Error(w, errTooManyImages.Error(), http.StatusBadRequest)
+			return
+		}
+		if added, err = saveReviewImages(r); err != nil {
+			http.Error(w, "Failed to save images: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(removed) > 0 || len(added) > 0 {
+			updatedFields["attachments"] = append(kept, added...)
+		}
+	}
+
+	var text []string
+	for _, field := range []string{"comment", "content"} {
+		if v, ok := updatedFields[field].(string); ok {
+			text = append(text, v)
+		}
+	}
+	verdict := contentfilter.Check(r.Context(), contentfilter.Input{UserID: review.UserID, EntityType: "review", Text: strings.Join(text, "\n")})
+	if verdict.Verdict == contentfilter.Reject {
+		removeReviewImages(added)
+		http.Error(w, "Review rejected: "+verdict.Reason, http.StatusUnprocessableEntity)
+		return
+	}
+	held := verdict.Verdict == contentfilter.Hold
+	if held {
+		updatedFields["hidden"] = true
+	}
+
+	newRating := review.Rating
+	if raw, ok := updatedFields["rating"]; ok {
+		rating, ok := raw.(float64)
+		if !ok || rating != math.Trunc(rating) || rating < 1 || rating > 5 {
+			removeReviewImages(added)
+			http.Error(w, "Invalid review data", http.StatusBadRequest)
+			return
+		}
+		newRating = int(rating)
+		updatedFields["rating"] = newRating
+	}
+	updatedFields["updated_at"] = time.Now()
+
+	err = withTransaction(r.Context(), func(sc mongo.SessionContext) error {
+		if _, err := db.ReviewsCollection.UpdateOne(sc, bson.M{"reviewid": reviewId}, bson.M{"$set": updatedFields}); err != nil {
+			return err
+		}
+		// Hidden reviews are already out of the aggregate.
+		switch {
+		case review.Hidden:
+			return nil
+		case held:
+			return applyRatingChange(sc, review.EntityType, review.EntityID, review.Rating, 0)
+		}
+		return applyRatingChange(sc, review.EntityType, review.EntityID, review.Rating, newRating)
+	})
 	if err != nil {
+		removeReviewImages(added)
 		http.Error(w, fmt.Sprintf("Failed to update Review: %v", err), http.StatusInternalServerError)
 		return
 	}
+	removeReviewImages(removed)
+	if held {
+		if err := contentfilter.HoldForReview(r.Context(), "review", reviewId, review.UserID, verdict, true); err != nil {
+			log.Printf("Failed to queue held review %s: %v", reviewId, err)
+		}
+	}
 
 	m := mq.Index{EntityType: "review", EntityId: reviewId, Method: "PUT", ItemId: review.EntityID, ItemType: review.EntityType}
 	go mq.Emit("review-edited", m)
@@ -187,16 +329,29 @@ func DeleteReview(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	if review.UserID != userId && !isAdmin(r.Context()) {
+	if review.UserID != userId && !middleware.IsModerator(r.Context(), userId) {
 		http.Error(w, "Forbidden", http.StatusForbidden)
 		return
 	}
 
-	_, err = db.ReviewsCollection.DeleteOne(context.TODO(), bson.M{"reviewid": reviewId})
+	err = withTransaction(r.Context(), func(sc mongo.SessionContext) error {
+		res, err := db.ReviewsCollection.DeleteOne(sc, bson.M{"reviewid": reviewId})
+		if err != nil || res.DeletedCount == 0 {
+			return err
+		}
+		if _, err := db.ReviewVotesCollection.DeleteMany(sc, bson.M{"reviewid": reviewId}); err != nil {
+			return err
+		}
+		if review.Hidden {
+			return nil
+		}
+		return applyRatingChange(sc, review.EntityType, review.EntityID, review.Rating, 0)
+	})
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to delete review: %v", err), http.StatusInternalServerError)
 		return
 	}
+	removeReviewImages(review.Attachments)
 
 	m := mq.Index{EntityType: "review", EntityId: reviewId, Method: "DELETE", ItemId: review.EntityID, ItemType: review.EntityType}
 	go mq.Emit("review-deleted", m)
@@ -225,6 +380,15 @@ func parseQueryParams(r *http.Request) (int64, int64, bson.M, bson.D) {
 		ratingVal, _ := strconv.Atoi(rating)
 		filters["rating"] = ratingVal
 	}
+	if query.Get("withMedia") == "true" {
+		filters["attachments.0"] = bson.M{"$exists": true}
+	}
+	switch query.Get("verified") {
+	case "true":
+		filters["verified_purchase"] = true
+	case "false":
+		filters["verified_purchase"] = bson.M{"$ne": true}
+	}
 
 	sort := bson.D{}
 	switch query.Get("sort") {
@@ -232,14 +396,15 @@ func parseQueryParams(r *http.Request) (int64, int64, bson.M, bson.D) {
 		sort = bson.D{{Key: "date", Value: 1}}
 	case "date_desc":
 		sort = bson.D{{Key: "date", Value: -1}}
+	case "rating_desc":
+		sort = bson.D{{Key: "rating", Value: -1}, {Key: "date", Value: -1}}
+	case "rating_asc":
+		sort = bson.D{{Key: "rating", Value: 1}, {Key: "date", Value: -1}}
+	case "verified":
+		sort = bson.D{{Key: "verified_purchase", Value: -1}, {Key: "date", Value: -1}}
+	case "helpful":
+		sort = bson.D{{Key: "helpful_count", Value: -1}, {Key: "unhelpful_count", Value: 1}, {Key: "date", Value: -1}}
 	}
 
 	return skip, int64(limit), filters, sort
 }
-
-func isAdmin(ctx context.Context) bool {
-	role, ok := ctx.Value(roleKey).(string)
-	return ok && role == "admin"
-}
-
-const roleKey = "role"
@@ -0,0 +1,73 @@
+package reviews
+
+import (
+	"context"
+	"errors"
+
+	"naevis/db"
+	"naevis/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errVerifiedBuyersOnly = errors.New("only verified buyers can review this farm")
+
+// userIDValues matches order userId fields stored either as the raw string
+// or as an ObjectID.
+func userIDValues(userID string) bson.M {
+	values := bson.A{userID}
+	if oid, err := primitive.ObjectIDFromHex(userID); err == nil {
+		values = append(values, oid)
+	}
+	return bson.M{"$in": values}
+}
+
+// isVerifiedPurchase reports whether userID has a delivered FarmOrder for
+// the reviewed farm or crop. Only farm orders count: their status is set by
+// the farm, while cart orders never leave the "pending" they are placed
+// with. Other entity types are never verified.
+func isVerifiedPurchase(ctx context.Context, userID, entityType, entityID string) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return false, nil
+	}
+
+	farmOrder := bson.M{"userId": userIDValues(userID), "status": "delivered"}
+	switch entityType {
+	case "farm":
+		farmOrder["farmId"] = oid
+	case "crop":
+		farmOrder["cropId"] = oid
+	default:
+		return false, nil
+	}
+
+	n, err := db.FarmOrdersCollection.CountDocuments(ctx, farmOrder)
+	return n > 0, err
+}
+
+// requiresVerifiedBuyer reports whether the farm behind a farm or crop
+// review target only accepts reviews from verified buyers.
+func requiresVerifiedBuyer(ctx context.Context, entityType, entityID string) bool {
+	oid, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return false
+	}
+
+	farmID := oid
+	switch entityType {
+	case "farm":
+	case "crop":
+		var crop models.Crop
+		if err := db.CropsCollection.FindOne(ctx, bson.M{"_id": oid}).Decode(&crop); err != nil {
+			return false
+		}
+		farmID = crop.FarmID
+	default:
+		return false
+	}
+
+	n, err := db.FarmsCollection.CountDocuments(ctx, bson.M{"_id": farmID, "verifiedBuyersOnly": true})
+	return err == nil && n > 0
+}
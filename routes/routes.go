@@ -2,17 +2,24 @@ package routes
 
 import (
 	"naevis/admin"
+	"naevis/apitokens"
 	"naevis/auth"
+	"naevis/blocks"
 	"naevis/cart"
 	"naevis/chats"
 	"naevis/comments"
 	"naevis/discord"
 	"naevis/farms"
 	"naevis/home"
+	"naevis/jwtkeys"
 	"naevis/middleware"
+	"naevis/moderation"
 	"naevis/newchat"
+	"naevis/notifications"
+	"naevis/privacy"
 	"naevis/profile"
 	"naevis/ratelim"
+	"naevis/reactions"
 	"naevis/recipes"
 	"naevis/reports"
 	"naevis/reviews"
@@ -41,10 +48,22 @@ func AddStaticRoutes(router *httprouter.Router) {
 	router.ServeFiles("/static/chatpic/*filepath", http.Dir("static/chatpic"))
 	router.ServeFiles("/static/newchatpic/*filepath", http.Dir("static/newchatpic"))
 	router.ServeFiles("/static/threadpic/*filepath", http.Dir("static/threadpic"))
+	router.ServeFiles("/static/reviewpic/*filepath", http.Dir("static/reviewpic"))
 }
 
 func AddAdminRoutes(router *httprouter.Router) {
-	router.GET("/api/v1/admin/reports", middleware.Authenticate(admin.GetReports))
+	moderatorOnly := middleware.RequireRole(middleware.RoleModerator)
+	adminOnly := middleware.RequireRole(middleware.RoleAdmin)
+
+	router.GET("/api/v1/admin/reports", middleware.Authenticate(moderatorOnly(admin.GetReports)))
+	router.GET("/api/v1/admin/report-targets", middleware.Authenticate(moderatorOnly(admin.GetReportTargets)))
+	router.GET("/api/v1/admin/report-targets/:targettype/:targetid", middleware.Authenticate(moderatorOnly(admin.GetTargetReports)))
+	router.POST("/api/v1/admin/report-targets/:targettype/:targetid/actions", middleware.Authenticate(moderatorOnly(moderation.ApplyAction)))
+	router.DELETE("/api/v1/admin/report-targets/:targettype/:targetid/actions/:actionid", middleware.Authenticate(moderatorOnly(moderation.RevertAction)))
+	router.GET("/api/v1/admin/verifications", middleware.Authenticate(adminOnly(admin.GetVerificationRequests)))
+	router.PUT("/api/v1/admin/verifications/:id", middleware.Authenticate(adminOnly(admin.ReviewVerification)))
+	router.GET("/api/v1/admin/users/:userid/roles", middleware.Authenticate(adminOnly(admin.GetUserRoles)))
+	router.PUT("/api/v1/admin/users/:userid/roles", middleware.Authenticate(adminOnly(admin.SetUserRoles)))
 }
 
 func AddRecipeRoutes(router *httprouter.Router) {
@@ -101,26 +120,68 @@ func AddHomeRoutes(router *httprouter.Router) {
 
 func AddReportRoutes(router *httprouter.Router) {
 	router.POST("/api/v1/report", ratelim.RateLimit(middleware.Authenticate(reports.ReportContent)))
-	router.GET("/api/v1/reports", ratelim.RateLimit(middleware.Authenticate(reports.GetReports)))
-	router.PUT("/api/v1/report/:id", ratelim.RateLimit(middleware.Authenticate(reports.UpdateReport)))
+	moderatorOnly := middleware.RequireRole(middleware.RoleModerator)
+
+	router.GET("/api/v1/reports", ratelim.RateLimit(middleware.Authenticate(moderatorOnly(reports.GetReports))))
+	router.PUT("/api/v1/report/:id", ratelim.RateLimit(middleware.Authenticate(moderatorOnly(reports.UpdateReport))))
+}
+
+func AddNotificationRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/notifications", middleware.Authenticate(notifications.GetNotifications))
+	router.POST("/api/v1/notifications/read-all", middleware.Authenticate(notifications.MarkAllNotificationsRead))
+	router.PUT("/api/v1/notifications/:id", middleware.Authenticate(notifications.MarkNotificationRead))
+}
+
+func AddReactionRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/reactions", reactions.GetReactionSet)
+	router.GET("/api/v1/reactions/:entitytype/:entityid", middleware.OptionalAuth(reactions.GetReactions))
+	router.POST("/api/v1/reactions/:entitytype/:entityid", ratelim.RateLimit(middleware.Authenticate(reactions.ToggleReaction)))
+}
+
+func AddBlockRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/blocks", middleware.Authenticate(blocks.GetBlocks))
+	router.PUT("/api/v1/blocks/:userid", ratelim.RateLimit(middleware.Authenticate(blocks.BlockUser)))
+	router.DELETE("/api/v1/blocks/:userid", ratelim.RateLimit(middleware.Authenticate(blocks.UnblockUser)))
 }
 
 func AddCommentsRoutes(router *httprouter.Router) {
-	router.POST("/api/v1/comments/:entitytype/:entityid", comments.CreateComment)
-	router.GET("/api/v1/comments/:entitytype/:entityid", comments.GetComments)
-	router.GET("/api/v1/comments/:entitytype", comments.GetComment)
-	router.PUT("/api/v1/comments/:entitytype/:entityid/:commentid", comments.UpdateComment)
-	router.DELETE("/api/v1/comments/:entitytype/:entityid/:commentid", comments.DeleteComment)
+	router.POST("/api/v1/comments/:entitytype/:entityid", middleware.Authenticate(comments.CreateComment))
+	router.GET("/api/v1/comments/:entitytype/:entityid", middleware.OptionalAuth(comments.GetComments))
+	router.GET("/api/v1/comments/:entitytype/:entityid/:commentid", middleware.OptionalAuth(comments.GetComment))
+	router.PUT("/api/v1/comments/:entitytype/:entityid/:commentid", middleware.Authenticate(comments.UpdateComment))
+	router.DELETE("/api/v1/comments/:entitytype/:entityid/:commentid", middleware.Authenticate(comments.DeleteComment))
+	router.GET("/api/v1/comments/:entitytype/:entityid/:commentid/replies", middleware.OptionalAuth(comments.GetReplies))
+	router.GET("/api/v1/commentcounts/:entitytype", ratelim.RateLimit(comments.GetCommentCounts))
+}
+
+func AddAPITokenRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/tokens", middleware.Authenticate(apitokens.GetTokens))
+	router.POST("/api/v1/tokens", middleware.Authenticate(apitokens.CreateToken))
+	router.DELETE("/api/v1/tokens/:id", middleware.Authenticate(apitokens.DeleteToken))
 }
 
 func AddAuthRoutes(router *httprouter.Router) {
+	router.GET("/.well-known/jwks.json", jwtkeys.JWKS)
 	router.POST("/api/v1/auth/register", ratelim.RateLimit(auth.Register))
 	router.POST("/api/v1/auth/login", ratelim.RateLimit(auth.Login))
+	router.POST("/api/v1/auth/login/mfa", ratelim.RateLimit(auth.LoginMFA))
 	router.POST("/api/v1/auth/logout", middleware.Authenticate(auth.LogoutUser))
-	router.POST("/api/v1/auth/token/refresh", ratelim.RateLimit(middleware.Authenticate(auth.RefreshToken)))
+	router.POST("/api/v1/auth/token/refresh", ratelim.RateLimit(auth.RefreshToken))
+	router.GET("/api/v1/auth/sessions", middleware.Authenticate(auth.GetSessions))
+	router.DELETE("/api/v1/auth/sessions", middleware.Authenticate(auth.RevokeOtherSessions))
+	router.DELETE("/api/v1/auth/sessions/:id", middleware.Authenticate(auth.RevokeSession))
+	router.GET("/api/v1/auth/security-events", middleware.Authenticate(auth.GetSecurityEvents))
+	router.GET("/api/v1/auth/oidc", auth.OIDCProviders)
+	router.POST("/api/v1/auth/oidc/:provider/start", ratelim.RateLimit(auth.StartOIDCLogin))
+	router.POST("/api/v1/auth/oidc/:provider/callback", ratelim.RateLimit(auth.OIDCCallback))
+	router.POST("/api/v1/auth/mfa/setup", middleware.Authenticate(auth.SetupMFA))
+	router.POST("/api/v1/auth/mfa/enable", ratelim.RateLimit(middleware.Authenticate(auth.EnableMFA)))
+	router.POST("/api/v1/auth/mfa/disable", ratelim.RateLimit(middleware.Authenticate(auth.DisableMFA)))
 
 	router.POST("/api/v1/auth/verify-otp", ratelim.RateLimit(auth.VerifyOTPHandler))
-	router.POST("/api/v1/auth/request-otp", ratelim.RateLimit(auth.VerifyOTPHandler))
+	router.POST("/api/v1/auth/request-otp", ratelim.RateLimit(auth.RequestOTPHandler))
+	router.POST("/api/v1/auth/password/request-reset", ratelim.RateLimit(auth.RequestPasswordReset))
+	router.POST("/api/v1/auth/password/confirm-reset", ratelim.RateLimit(auth.ConfirmPasswordReset))
 }
 
 func AddCartRoutes(router *httprouter.Router) {
@@ -181,26 +242,36 @@ func RegisterFarmRoutes(router *httprouter.Router) {
 	router.POST("/api/v1/farms", middleware.Authenticate(farms.CreateFarm))
 	router.GET("/api/v1/farms", farms.GetPaginatedFarms)
 	router.GET("/api/v1/farms/:id", middleware.OptionalAuth(farms.GetFarm))
-	router.PUT("/api/v1/farms/:id", middleware.Authenticate(farms.EditFarm))
+	router.PUT("/api/v1/farms/:id", middleware.Authenticate(farms.EditFarm, middleware.ScopeFarmsWrite))
 	router.DELETE("/api/v1/farms/:id", middleware.Authenticate(farms.DeleteFarm))
+	router.POST("/api/v1/farms/:id/restore", middleware.Authenticate(farms.RestoreFarm))
+	router.PUT("/api/v1/farms/:id/favorite", middleware.Authenticate(farms.FavoriteFarm))
+	router.DELETE("/api/v1/farms/:id/favorite", middleware.Authenticate(farms.UnfavoriteFarm))
+	router.PUT("/api/v1/farms/:id/review-policy", middleware.Authenticate(farms.UpdateReviewPolicy))
+	router.GET("/api/v1/favorites/farms", middleware.Authenticate(farms.GetFavoriteFarms))
+
+	// ✅ Verification
+	router.POST("/api/v1/farms/:id/verification", middleware.Authenticate(farms.SubmitFarmVerification))
+	router.GET("/api/v1/farms/:id/verification", middleware.Authenticate(farms.GetFarmVerification, middleware.ScopeFarmsRead))
 
 	// 🌱 Crops (within farm)
-	router.POST("/api/v1/farms/:id/crops", middleware.Authenticate(farms.AddCrop))
-	router.PUT("/api/v1/farms/:id/crops/:cropid", middleware.Authenticate(farms.EditCrop))
-	router.DELETE("/api/v1/farms/:id/crops/:cropid", middleware.Authenticate(farms.DeleteCrop))
+	router.POST("/api/v1/farms/:id/crops", middleware.Authenticate(farms.AddCrop, middleware.ScopeCropsWrite))
+	router.PUT("/api/v1/farms/:id/crops/:cropid", middleware.Authenticate(farms.EditCrop, middleware.ScopeCropsWrite))
+	router.DELETE("/api/v1/farms/:id/crops/:cropid", middleware.Authenticate(farms.DeleteCrop, middleware.ScopeCropsWrite))
+	router.POST("/api/v1/farms/:id/crops/:cropid/restore", middleware.Authenticate(farms.RestoreCrop, middleware.ScopeCropsWrite))
 	router.PUT("/api/v1/farms/:id/crops/:cropid/buy", middleware.Authenticate(farms.BuyCrop))
 
 	// 📊 Dashboard
-	router.GET("/api/v1/dash/farms", middleware.Authenticate(farms.GetFarmDash))
+	router.GET("/api/v1/dash/farms", middleware.Authenticate(farms.GetFarmDash, middleware.ScopeFarmsRead))
 
 	// 📦 Farm Orders
-	router.GET("/api/v1/orders/mine", middleware.Authenticate(farms.GetMyFarmOrders))           // my own farm orders
-	router.GET("/api/v1/orders/incoming", middleware.Authenticate(farms.GetIncomingFarmOrders)) // orders from buyers to me
-	router.POST("/api/v1/farmorders/:id/accept", middleware.Authenticate(farms.AcceptOrder))
-	router.POST("/api/v1/farmorders/:id/reject", middleware.Authenticate(farms.RejectOrder))
-	router.POST("/api/v1/farmorders/:id/deliver", middleware.Authenticate(farms.MarkOrderDelivered))
-	router.POST("/api/v1/farmorders/:id/markpaid", middleware.Authenticate(farms.MarkOrderPaid))
-	router.GET("/api/v1/farmorders/:id/receipt", middleware.Authenticate(farms.DownloadReceipt))
+	router.GET("/api/v1/orders/mine", middleware.Authenticate(farms.GetMyFarmOrders, middleware.ScopeOrdersRead))           // my own farm orders
+	router.GET("/api/v1/orders/incoming", middleware.Authenticate(farms.GetIncomingFarmOrders, middleware.ScopeOrdersRead)) // orders from buyers to me
+	router.POST("/api/v1/farmorders/:id/accept", middleware.Authenticate(farms.AcceptOrder, middleware.ScopeOrdersWrite))
+	router.POST("/api/v1/farmorders/:id/reject", middleware.Authenticate(farms.RejectOrder, middleware.ScopeOrdersWrite))
+	router.POST("/api/v1/farmorders/:id/deliver", middleware.Authenticate(farms.MarkOrderDelivered, middleware.ScopeOrdersWrite))
+	router.POST("/api/v1/farmorders/:id/markpaid", middleware.Authenticate(farms.MarkOrderPaid, middleware.ScopeOrdersWrite))
+	router.GET("/api/v1/farmorders/:id/receipt", middleware.Authenticate(farms.DownloadReceipt, middleware.ScopeOrdersRead))
 
 	// 🌾 Crop catalogue & type browsing
 	router.GET("/api/v1/crops", farms.GetFilteredCrops)                                         // for search/filter
@@ -208,6 +279,9 @@ func RegisterFarmRoutes(router *httprouter.Router) {
 	router.GET("/api/v1/crops/precatalogue", farms.GetPreCropCatalogue)                         // pre-published
 	router.GET("/api/v1/crops/types", farms.GetCropTypes)                                       // types list
 	router.GET("/api/v1/crops/crop/:cropname", middleware.OptionalAuth(farms.GetCropTypeFarms)) // farms by crop name
+	router.GET("/api/v1/crops/watchlist", middleware.Authenticate(farms.GetCropWatchlist))
+	router.POST("/api/v1/crops/watchlist", middleware.Authenticate(farms.WatchCrop))
+	router.DELETE("/api/v1/crops/watchlist/:cropname", middleware.Authenticate(farms.UnwatchCrop))
 
 	// 🛒 Items, Products, Tools
 	// -- GET
@@ -231,6 +305,15 @@ func RegisterFarmRoutes(router *httprouter.Router) {
 	router.POST("/api/v1/upload/images", utils.UploadImages)
 }
 
+func AddPrivacyRoutes(router *httprouter.Router) {
+	router.GET("/api/v1/privacy/export", middleware.Authenticate(privacy.GetExports))
+	router.POST("/api/v1/privacy/export", middleware.Authenticate(privacy.RequestExport))
+	router.GET("/api/v1/privacy/export/:id/download", middleware.Authenticate(privacy.DownloadExport))
+	router.GET("/api/v1/privacy/erasure", middleware.Authenticate(privacy.GetErasure))
+	router.POST("/api/v1/privacy/erasure", middleware.Authenticate(privacy.RequestErasure))
+	router.DELETE("/api/v1/privacy/erasure", middleware.Authenticate(privacy.CancelErasure))
+}
+
 func AddSuggestionsRoutes(router *httprouter.Router) {
 	router.GET("/api/v1/suggestions/follow", ratelim.RateLimit(middleware.Authenticate(suggestions.SuggestFollowers)))
 }
@@ -241,6 +324,10 @@ func AddReviewsRoutes(router *httprouter.Router) {
 	router.POST("/api/v1/reviews/:entityType/:entityId", ratelim.RateLimit(middleware.Authenticate(reviews.AddReview)))
 	router.PUT("/api/v1/reviews/:entityType/:entityId/:reviewId", ratelim.RateLimit(middleware.Authenticate(reviews.EditReview)))
 	router.DELETE("/api/v1/reviews/:entityType/:entityId/:reviewId", ratelim.RateLimit(middleware.Authenticate(reviews.DeleteReview)))
+	router.PUT("/api/v1/reviews/:entityType/:entityId/:reviewId/response", ratelim.RateLimit(middleware.Authenticate(reviews.RespondToReview)))
+	router.DELETE("/api/v1/reviews/:entityType/:entityId/:reviewId/response", ratelim.RateLimit(middleware.Authenticate(reviews.DeleteReviewResponse)))
+	router.PUT("/api/v1/reviews/:entityType/:entityId/:reviewId/vote", ratelim.RateLimit(middleware.Authenticate(reviews.VoteReview)))
+	router.DELETE("/api/v1/reviews/:entityType/:entityId/:reviewId/vote", ratelim.RateLimit(middleware.Authenticate(reviews.UnvoteReview)))
 }
 
 func AddProfileRoutes(router *httprouter.Router) {
@@ -248,7 +335,11 @@ func AddProfileRoutes(router *httprouter.Router) {
 	router.PUT("/api/v1/profile/edit", middleware.Authenticate(profile.EditProfile))
 	router.PUT("/api/v1/profile/avatar", middleware.Authenticate(profile.EditProfilePic))
 	router.PUT("/api/v1/profile/banner", middleware.Authenticate(profile.EditProfileBanner))
-	router.DELETE("/api/v1/profile/delete", middleware.Authenticate(profile.DeleteProfile))
+	router.DELETE("/api/v1/profile/delete", middleware.Authenticate(privacy.RequestErasure))
+	router.GET("/api/v1/profile/identities", middleware.Authenticate(auth.GetIdentities))
+	router.POST("/api/v1/profile/identities/:provider/start", middleware.Authenticate(auth.StartLinkIdentity))
+	router.POST("/api/v1/profile/identities/:provider", ratelim.RateLimit(middleware.Authenticate(auth.LinkIdentity)))
+	router.DELETE("/api/v1/profile/identities/:provider", middleware.Authenticate(auth.UnlinkIdentity))
 
 	router.GET("/api/v1/user/:username", ratelim.RateLimit(profile.GetUserProfile))
 
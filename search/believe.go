@@ -13,6 +13,7 @@ import (
 
 	"github.com/redis/go-redis/v9"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -161,6 +162,18 @@ func GetResultsByType(entityType string, id string) any {
 			log.Println("p:", p)
 			result = p
 		}
+	case "farm":
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil
+		}
+		var f models.Farm
+		if err := FetchAndDecode("farms", bson.M{"_id": oid}, &f); err != nil {
+			log.Println("Error fetching farm from primary collection, error:", err)
+			result = FetchEntityFromSearchDB(id)
+		} else {
+			result = f
+		}
 	default:
 		return nil
 	}
@@ -186,6 +199,12 @@ func ConvertToEntity(data any) (Entity, error) {
 		entity.Description = v.Description
 		entity.Type = "place"
 		entity.CreatedAt = parseTime(v.CreatedAt)
+	case models.Farm:
+		entity.ID = v.FarmID.Hex()
+		entity.Title = v.Name
+		entity.Description = v.Description + " " + v.Location
+		entity.Type = "farm"
+		entity.CreatedAt = v.CreatedAt
 	case Entity:
 		// In case we already have an Entity from the search collection.
 		entity = v
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"log"
+	"sort"
+
+	"naevis/globals"
+	"naevis/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FarmSearchOptions narrows farm results beyond the text query.
+type FarmSearchOptions struct {
+	VerifiedOnly  bool // drop farms that have not been verified
+	BoostVerified bool // list verified farms ahead of unverified ones
+}
+
+// GetFarmResults resolves indexed farm IDs for a query, hiding deleted farms
+// and applying the verification filter/boost.
+func GetFarmResults(query string, opts FarmSearchOptions) []models.Farm {
+	ids, err := GetIndexResults("farms", query)
+	if err != nil {
+		log.Println("Error searching farms:", err)
+		return []models.Farm{}
+	}
+
+	// The index is shared with events and places; only ObjectIDs can be farms.
+	rank := make(map[primitive.ObjectID]int, len(ids))
+	var farmIDs []primitive.ObjectID
+	for i, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			rank[oid] = i
+			farmIDs = append(farmIDs, oid)
+		}
+	}
+	if len(farmIDs) == 0 {
+		return []models.Farm{}
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": farmIDs}, "deletedAt": nil}
+	if opts.VerifiedOnly {
+		filter["verified"] = true
+	}
+
+	collection := globals.MongoClient.Database("eventdb").Collection("farms")
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		log.Println("Error fetching farms:", err)
+		return []models.Farm{}
+	}
+	defer cursor.Close(ctx)
+
+	farms := []models.Farm{}
+	if err := cursor.All(ctx, &farms); err != nil {
+		log.Println("Error decoding farms:", err)
+		return []models.Farm{}
+	}
+
+	// Keep index relevance order, optionally with verified farms first.
+	sort.SliceStable(farms, func(i, j int) bool {
+		if opts.BoostVerified && farms[i].Verified != farms[j].Verified {
+			return farms[i].Verified
+		}
+		return rank[farms[i].FarmID] < rank[farms[j].FarmID]
+	})
+	return farms
+}
@@ -24,7 +24,15 @@ func GetResultsByTypeHandler(w http.ResponseWriter, r *http.Request, entityType
 	}
 
 	// Get results based on the reverse index
-	results := GetResultsOfType(entityType, query)
+	var results interface{}
+	if entityType == "farms" {
+		results = GetFarmResults(query, FarmSearchOptions{
+			VerifiedOnly:  r.URL.Query().Get("verified") == "true",
+			BoostVerified: r.URL.Query().Get("boost") == "verified",
+		})
+	} else {
+		results = GetResultsOfType(entityType, query)
+	}
 
 	// Convert results slice (or map for "all") to JSON and send response
 	response, err := json.Marshal(results)
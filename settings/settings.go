@@ -19,6 +19,7 @@ type UserSettings struct {
 	UserID        string `json:"userID,omitempty" bson:"userID"`
 	Theme         string `json:"theme" bson:"theme"`
 	Notifications bool   `json:"notifications" bson:"notifications"`
+	EmailNotices  bool   `json:"email_notifications" bson:"email_notifications"`
 	PrivacyMode   bool   `json:"privacy_mode" bson:"privacy_mode"`
 	AutoLogout    bool   `json:"auto_logout" bson:"auto_logout"`
 	Language      string `json:"language" bson:"language"`
@@ -59,6 +60,7 @@ func GetUserSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 	settingsArray := []map[string]any{
 		{"type": "theme", "value": userSettings.Theme, "description": "Choose theme mode"},
 		{"type": "notifications", "value": userSettings.Notifications, "description": "Enable notifications"},
+		{"type": "email_notifications", "value": userSettings.EmailNotices, "description": "Also send notifications by email"},
 		{"type": "privacy_mode", "value": userSettings.PrivacyMode, "description": "Enable privacy mode"},
 		{"type": "auto_logout", "value": userSettings.AutoLogout, "description": "Enable auto logout"},
 		{"type": "language", "value": userSettings.Language, "description": "Select language"},
@@ -76,13 +78,14 @@ func UpdateUserSetting(w http.ResponseWriter, r *http.Request, ps httprouter.Par
 	settingType := ps.ByName("type")
 
 	validSettings := map[string]bool{
-		"theme":          true,
-		"notifications":  true,
-		"privacy_mode":   true,
-		"auto_logout":    true,
-		"language":       true,
-		"time_zone":      true,
-		"daily_reminder": true,
+		"theme":               true,
+		"notifications":       true,
+		"email_notifications": true,
+		"privacy_mode":        true,
+		"auto_logout":         true,
+		"language":            true,
+		"time_zone":           true,
+		"daily_reminder":      true,
 	}
 	if !validSettings[settingType] {
 		http.Error(w, "Invalid setting type", http.StatusBadRequest)
@@ -147,3 +150,11 @@ func InitUserSettings(w http.ResponseWriter, r *http.Request, ps httprouter.Para
 	// If settings already exist, return false
 	json.NewEncoder(w).Encode(false)
 }
+
+// EmailNotificationsEnabled reports whether userID has opted in to getting
+// notifications by email as well as in the app.
+func EmailNotificationsEnabled(ctx context.Context, userID string) bool {
+	var s UserSettings
+	err := db.SettingsCollection.FindOne(ctx, bson.M{"userID": userID}).Decode(&s)
+	return err == nil && s.EmailNotices
+}
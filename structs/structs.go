@@ -31,6 +31,24 @@ type User struct {
 	PasswordHash   string            `json:"password_hash" bson:"password_hash"`
 	Followerscount int               `json:"followerscount" bson:"followerscount"`
 	Followcount    int               `json:"followscount" bson:"followscount"`
+	Warnings       int               `json:"warnings,omitempty" bson:"warnings,omitempty"`
+	SuspendedUntil *time.Time        `json:"suspended_until,omitempty" bson:"suspended_until,omitempty"`
+	MFAEnabled     bool              `json:"mfa_enabled" bson:"mfa_enabled"`
+	MFASecret      string            `json:"-" bson:"mfa_secret,omitempty"`
+	MFAPending     string            `json:"-" bson:"mfa_pending_secret,omitempty"`                    // secret awaiting its first code
+	MFALastStep    int64             `json:"-" bson:"mfa_last_step,omitempty"`                         // last TOTP step used, against replay
+	RecoveryCodes  []string          `json:"-" bson:"mfa_recovery_codes,omitempty"`                    // hashed, each usable once
+	Identities     []LinkedIdentity  `json:"identities,omitempty" bson:"identities,omitempty"`         // external sign-in accounts
+	ErasureDueAt   *time.Time        `json:"erasure_due_at,omitempty" bson:"erasure_due_at,omitempty"` // account erasure is scheduled for then
+}
+
+// LinkedIdentity is an account at an OpenID Connect provider that can sign
+// in as the user. A user links at most one per provider.
+type LinkedIdentity struct {
+	Provider string    `json:"provider" bson:"provider"`
+	Subject  string    `json:"-" bson:"subject"`
+	Email    string    `json:"email,omitempty" bson:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at" bson:"linked_at"`
 }
 
 // UserProfileResponse defines the structure for the user profile response
@@ -242,19 +260,40 @@ type Suggestion struct {
 }
 
 type Review struct {
-	EntityID    string    `json:"entity_id" bson:"entity_id"`
-	EntityType  string    `json:"entity_type" bson:"entity_type"` // "event" or "place"
-	Comment     string    `json:"comment,omitempty" bson:"comment,omitempty"`
-	UpdatedAt   time.Time `json:"updated_at" bson:"updated_at"`
-	Content     string    `bson:"content" json:"content"`
-	ReviewID    string    `json:"reviewid" bson:"reviewid"`
-	UserID      string    `json:"userid" bson:"userid"` // Reference to User ID
-	Rating      int       `json:"rating" bson:"rating"` // Rating out of 5
-	Date        time.Time `json:"date" bson:"date"`     // Date of the review
-	Likes       int       `json:"likes,omitempty" bson:"likes,omitempty"`
-	Dislikes    int       `json:"dislikes,omitempty" bson:"dislikes,omitempty"`
-	Attachments []string  `json:"attachments,omitempty" bson:"attachments,omitempty"`
-	CreatedAt   string    `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	EntityID    string          `json:"entity_id" bson:"entity_id"`
+	EntityType  string          `json:"entity_type" bson:"entity_type"` // "event" or "place"
+	Comment     string          `json:"comment,omitempty" bson:"comment,omitempty"`
+	UpdatedAt   time.Time       `json:"updated_at" bson:"updated_at"`
+	Content     string          `bson:"content" json:"content"`
+	ReviewID    string          `json:"reviewid" bson:"reviewid"`
+	UserID      string          `json:"userid" bson:"userid"` // Reference to User ID
+	Rating      int             `json:"rating" bson:"rating"` // Rating out of 5
+	Date        time.Time       `json:"date" bson:"date"`     // Date of the review
+	Likes       int             `json:"likes,omitempty" bson:"likes,omitempty"`
+	Dislikes    int             `json:"dislikes,omitempty" bson:"dislikes,omitempty"`
+	Attachments []string        `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	CreatedAt   string          `json:"createdAt,omitempty" bson:"createdAt,omitempty"`
+	Verified    bool            `json:"verifiedPurchase" bson:"verified_purchase"` // reviewer has a delivered order for the target
+	Helpful     int             `json:"helpful" bson:"helpful_count"`
+	Unhelpful   int             `json:"unhelpful" bson:"unhelpful_count"`
+	Response    *ReviewResponse `json:"response,omitempty" bson:"response,omitempty"`
+	Hidden      bool            `json:"hidden,omitempty" bson:"hidden,omitempty"` // held by the content filter or hidden by a moderator
+}
+
+// ReviewResponse is the reviewed entity owner's public reply to a review.
+type ReviewResponse struct {
+	UserID    string    `json:"userid" bson:"userid"`
+	Body      string    `json:"body" bson:"body"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ReviewVote records one user's helpful/unhelpful vote on a review.
+type ReviewVote struct {
+	ReviewID  string    `json:"reviewid" bson:"reviewid"`
+	UserID    string    `json:"userid" bson:"userid"`
+	Vote      string    `json:"vote" bson:"vote"` // "helpful" or "unhelpful"
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
 }
 
 type Media struct {
@@ -30,6 +30,9 @@ var ValidEntityTypes = map[string]bool{
 	"booking":    true,
 	"blogpost":   true,
 	"collection": true,
+	"farm":       true,
+	"crop":       true,
+	"cropwatch":  true,
 }
 
 func IsValidEntityType(entityType string) bool {